@@ -0,0 +1,209 @@
+// Package approval puts a maker-checker workflow in front of payouts.
+// A payout request records who asked for it; depending on its amount and
+// currency, a Policy requires approvals from other people before the
+// payout is forwarded to PayChangu. Unapproved requests expire, and every
+// action is recorded in a hash-chained audit trail.
+//
+// Example Usage:
+//
+//	service := &approval.Service{
+//	    Store: approval.NewSQLStore(db, paychangu.DialectDollar),
+//	    Policy: approval.Policy{"MWK": {
+//	        {Threshold: 100000, Approvals: 1},
+//	        {Threshold: 1000000, Approvals: 2},
+//	    }},
+//	    Directory: client,
+//	    Forwarder: approval.ClientForwarder{Client: client},
+//	    TTL:       24 * time.Hour,
+//	}
+//
+//	r, err := service.RequestMobileMoney(ctx, "maker@example.com", "MWK", payoutReq)
+//	// ... later, by someone else
+//	r, err = service.Approve(ctx, r.ID, "checker@example.com")
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Errors returned by the Service.
+var (
+	ErrNotFound = errors.New("approval: request not found")
+
+	// ErrSelfApproval is returned when the requester tries to approve
+	// their own request.
+	ErrSelfApproval = errors.New("approval: requester cannot approve their own request")
+
+	// ErrDuplicateApproval is returned when an approver approves twice.
+	ErrDuplicateApproval = errors.New("approval: already approved by this approver")
+
+	// ErrNotPending is returned when acting on a request that
+	// was already approved, rejected or expired.
+	ErrNotPending = errors.New("approval: request is not pending")
+
+	// ErrExpired is returned when approving a request past its expiry.
+	ErrExpired = errors.New("approval: request has expired")
+
+	// ErrConflict is returned when a request was changed
+	// by someone else since it was read.
+	ErrConflict = errors.New("approval: concurrent update")
+
+	// ErrNoPolicy is returned when the Policy has no levels for the
+	// currency of a request, nor levels for every currency.
+	ErrNoPolicy = errors.New("approval: no policy for currency")
+
+	// ErrInvalidAmount is returned for payouts whose amount is not
+	// a positive number.
+	ErrInvalidAmount = errors.New("approval: payout amount must be a positive number")
+
+	// ErrCurrencyMismatch is returned when the currency given with a
+	// request is not the one its operator or bank pays out in.
+	ErrCurrencyMismatch = errors.New("approval: currency does not match the payout")
+)
+
+// Level requires a number of approvals for payouts of at least Threshold.
+type Level struct {
+	Threshold float64 `json:"threshold"`
+	Approvals int     `json:"approvals"`
+}
+
+// Policy maps currency codes to approval levels. Currency codes are
+// matched regardless of case. The levels under the empty currency apply
+// to currencies without their own; without them, requests in other
+// currencies are refused. Payouts below every threshold need no
+// approval and are forwarded immediately.
+type Policy map[string][]Level
+
+// Required returns the number of approvals a payout of amount needs:
+// that of the highest level whose threshold the amount reaches. It
+// returns ErrNoPolicy if p has no levels that apply to currency, and
+// ErrInvalidAmount if amount is not a positive, finite number.
+func (p Policy) Required(currency string, amount float64) (int, error) {
+	if !(amount > 0) || math.IsInf(amount, 1) {
+		return 0, fmt.Errorf("%w: %g", ErrInvalidAmount, amount)
+	}
+	levels, ok := p.levels(currency)
+	if !ok {
+		levels, ok = p[""]
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrNoPolicy, currency)
+	}
+	levels = append([]Level(nil), levels...)
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Threshold < levels[j].Threshold })
+
+	required := 0
+	for _, level := range levels {
+		if amount >= level.Threshold {
+			required = level.Approvals
+		}
+	}
+	return required, nil
+}
+
+// levels returns the levels of currency, matched regardless of case.
+func (p Policy) levels(currency string) ([]Level, bool) {
+	currency = strings.TrimSpace(currency)
+	if currency == "" {
+		return nil, false
+	}
+	for code, levels := range p {
+		if strings.EqualFold(strings.TrimSpace(code), currency) {
+			return levels, true
+		}
+	}
+	return nil, false
+}
+
+// Status is the status of a request.
+type Status string
+
+// Request statuses.
+const (
+	// StatusPending requests are waiting for approvals.
+	StatusPending Status = "pending"
+
+	// StatusApproved requests have all approvals but were not yet
+	// forwarded, or forwarding failed and can be retried.
+	StatusApproved Status = "approved"
+
+	// StatusForwarding requests are being forwarded. The status is
+	// saved before the Forwarder is called, so that only one caller
+	// forwards a request. A request left forwarding by a crash may or
+	// may not have been paid: look its ChargeID up with PayChangu
+	// before doing anything else with it.
+	StatusForwarding Status = "forwarding"
+
+	// StatusForwarded requests were forwarded to PayChangu.
+	StatusForwarded Status = "forwarded"
+
+	// StatusRejected requests were rejected by an approver.
+	StatusRejected Status = "rejected"
+
+	// StatusExpired requests did not get their approvals in time.
+	StatusExpired Status = "expired"
+)
+
+// Approval is one approver's sign-off.
+type Approval struct {
+	By string    `json:"by"`
+	At time.Time `json:"at"`
+}
+
+// Request is a payout waiting for, or cleared by, approval.
+type Request struct {
+	ID string `json:"id"`
+
+	// MobileMoney or Bank holds the payout to make.
+	MobileMoney *paychangu.MobileMoneyPayoutRequest `json:"mobile_money,omitempty"`
+	Bank        *paychangu.BankPayoutRequest        `json:"bank,omitempty"`
+
+	Currency string `json:"currency"`
+
+	// Requester is the maker of the request.
+	Requester string `json:"requester"`
+
+	// Required is the number of approvals needed, fixed when the request was made.
+	Required  int        `json:"required"`
+	Approvals []Approval `json:"approvals"`
+
+	Status Status `json:"status"`
+
+	// LastError is the error of the last failed forward, if any.
+	LastError string `json:"last_error,omitempty"`
+
+	// Version is incremented by the Store on every update.
+	Version int `json:"version"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Amount returns the amount of the payout.
+func (r *Request) Amount() float64 {
+	if r.Bank != nil {
+		return r.Bank.Amount
+	}
+	if r.MobileMoney != nil {
+		return r.MobileMoney.Amount
+	}
+	return 0
+}
+
+// ChargeID returns the ChargeID of the payout.
+func (r *Request) ChargeID() string {
+	if r.Bank != nil {
+		return r.Bank.ChargeID
+	}
+	if r.MobileMoney != nil {
+		return r.MobileMoney.ChargeID
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package approval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Audit actions.
+const (
+	ActionRequested     = "requested"
+	ActionApproved      = "approved"
+	ActionRejected      = "rejected"
+	ActionExpired       = "expired"
+	ActionForwarded     = "forwarded"
+	ActionForwardFailed = "forward_failed"
+)
+
+// AuditEntry is one immutable record in the audit trail of a request.
+// Each entry holds the hash of the entry before it, so that altering
+// or removing an entry breaks the chain; see VerifyAudit.
+type AuditEntry struct {
+	RequestID string    `json:"request_id"`
+	Seq       int       `json:"seq"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Detail    string    `json:"detail,omitempty"`
+	At        time.Time `json:"at"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// hash computes the hash of e from its other fields.
+func (e AuditEntry) hash() string {
+	h := sha256.New()
+	for _, field := range []string{
+		e.RequestID, strconv.Itoa(e.Seq), e.Action, e.Actor, e.Detail,
+		e.At.UTC().Format(time.RFC3339Nano), e.PrevHash,
+	} {
+		h.Write([]byte(strconv.Itoa(len(field))))
+		h.Write([]byte{':'})
+		h.Write([]byte(field))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyAudit checks that entries form an unbroken hash chain,
+// as returned by Store.Audit for one request.
+func VerifyAudit(entries []AuditEntry) error {
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			return fmt.Errorf("audit entry %d has sequence number %d", i+1, e.Seq)
+		}
+		if e.PrevHash != prev {
+			return fmt.Errorf("audit entry %d does not follow entry %d", e.Seq, i)
+		}
+		if e.Hash != e.hash() {
+			return fmt.Errorf("audit entry %d was altered", e.Seq)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
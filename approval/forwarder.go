@@ -0,0 +1,87 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/payout"
+)
+
+// Forwarder makes the payout of an approved request.
+type Forwarder interface {
+	Forward(ctx context.Context, r *Request) error
+}
+
+// Client is the part of the PayChangu client used by ClientForwarder.
+type Client interface {
+	InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error)
+	InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error)
+}
+
+// ClientForwarder forwards approved payouts straight to PayChangu.
+// Configure the client with paychangu.WithIdempotencyStore so that
+// retried forwards cannot pay twice.
+type ClientForwarder struct {
+	Client Client
+}
+
+// Forward implements Forwarder.
+func (f ClientForwarder) Forward(ctx context.Context, r *Request) error {
+	switch {
+	case r.MobileMoney != nil:
+		_, err := f.Client.InitiateMobileMoneyPayoutContext(ctx, *r.MobileMoney)
+		return err
+	case r.Bank != nil:
+		_, err := f.Client.InitiateBankPayoutContext(ctx, *r.Bank)
+		return err
+	default:
+		return fmt.Errorf("approval: request %s has no payout", r.ID)
+	}
+}
+
+// MachineForwarder forwards approved payouts to a payout.Machine,
+// drafting and approving them there so that its Worker submits them.
+type MachineForwarder struct {
+	Machine *payout.Machine
+}
+
+// Forward implements Forwarder.
+func (f MachineForwarder) Forward(ctx context.Context, r *Request) error {
+	var err error
+	switch {
+	case r.MobileMoney != nil:
+		_, err = f.Machine.DraftMobileMoney(ctx, *r.MobileMoney)
+	case r.Bank != nil:
+		_, err = f.Machine.DraftBank(ctx, *r.Bank)
+	default:
+		return fmt.Errorf("approval: request %s has no payout", r.ID)
+	}
+	if err != nil {
+		// A retried forward finds the draft already created.
+		if _, getErr := f.Machine.Store.Get(ctx, r.ID); getErr != nil {
+			return err
+		}
+	}
+
+	approvers := ""
+	for i, a := range r.Approvals {
+		if i > 0 {
+			approvers += ", "
+		}
+		approvers += a.By
+	}
+	if approvers == "" {
+		approvers = "policy"
+	}
+
+	p, err := f.Machine.Store.Get(ctx, r.ID)
+	if err != nil {
+		return err
+	}
+	if p.State != payout.StateDraft {
+		return nil
+	}
+	_, err = f.Machine.Approve(ctx, r.ID, approvers)
+	return err
+}
@@ -0,0 +1,329 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Directory looks up the currencies that mobile money operators and
+// banks pay out in. The PayChangu client is a Directory.
+type Directory interface {
+	GetMobileMoneyOperatorsContext(ctx context.Context) ([]paychangu.MobileMoneyOperator, error)
+	GetSupportedBanksContext(ctx context.Context, currency string) ([]paychangu.Bank, error)
+}
+
+// Service records payout requests and approvals, and forwards
+// requests to PayChangu once they have all the approvals they need.
+type Service struct {
+	// Store persists the requests and audit trail. It is required.
+	Store Store
+
+	// Policy decides how many approvals a request needs. Requests in
+	// a currency it has no levels for are refused.
+	Policy Policy
+
+	// Directory checks the currency of each request against its
+	// operator or bank, so that the Policy for the right currency
+	// applies. It is required.
+	Directory Directory
+
+	// Forwarder makes approved payouts. It is required.
+	Forwarder Forwarder
+
+	// TTL is how long a request may wait for approvals.
+	// It defaults to 24 hours.
+	TTL time.Duration
+}
+
+// RequestMobileMoney records a mobile money payout requested by
+// requester. The currency is that of the payout's operator; if currency
+// is not empty it must match it, or ErrCurrencyMismatch is returned. A
+// ChargeID is generated if request has none. If the Policy requires no
+// approval, the payout is forwarded immediately.
+func (s *Service) RequestMobileMoney(ctx context.Context, requester, currency string, request paychangu.MobileMoneyPayoutRequest) (*Request, error) {
+	operators, err := s.Directory.GetMobileMoneyOperatorsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("approval: failed to look up operators: %w", err)
+	}
+	operatorCurrency := ""
+	for _, operator := range operators {
+		if operator.RefID == request.MobileMoneyOperatorRefID {
+			operatorCurrency = strings.ToUpper(operator.SupportedCountry.Currency)
+			break
+		}
+	}
+	if operatorCurrency == "" {
+		return nil, fmt.Errorf("approval: unknown mobile money operator %q", request.MobileMoneyOperatorRefID)
+	}
+	if currency != "" && !strings.EqualFold(strings.TrimSpace(currency), operatorCurrency) {
+		return nil, fmt.Errorf("%w: operator %s pays out in %s, not %s", ErrCurrencyMismatch, request.MobileMoneyOperatorRefID, operatorCurrency, currency)
+	}
+
+	if request.ChargeID == "" {
+		request.ChargeID = paychangu.NewReference("PAYOUT")
+	}
+	return s.create(ctx, &Request{MobileMoney: &request, Currency: operatorCurrency, Requester: requester})
+}
+
+// RequestBank records a bank payout requested by requester. The bank
+// must be one PayChangu supports for currency, or ErrCurrencyMismatch
+// is returned. A ChargeID is generated if request has none. If the
+// Policy requires no approval, the payout is forwarded immediately.
+func (s *Service) RequestBank(ctx context.Context, requester, currency string, request paychangu.BankPayoutRequest) (*Request, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return nil, fmt.Errorf("approval: currency is required")
+	}
+	banks, err := s.Directory.GetSupportedBanksContext(ctx, currency)
+	if err != nil {
+		return nil, fmt.Errorf("approval: failed to look up banks: %w", err)
+	}
+	supported := false
+	for _, bank := range banks {
+		if bank.UUID == request.BankUUID {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, fmt.Errorf("%w: bank %s does not pay out in %s", ErrCurrencyMismatch, request.BankUUID, currency)
+	}
+
+	if request.ChargeID == "" {
+		request.ChargeID = paychangu.NewReference("PAYOUT")
+	}
+	return s.create(ctx, &Request{Bank: &request, Currency: currency, Requester: requester})
+}
+
+// create saves a new request and forwards it if it needs no approval.
+func (s *Service) create(ctx context.Context, r *Request) (*Request, error) {
+	if r.Requester == "" {
+		return nil, fmt.Errorf("approval: requester is required")
+	}
+
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	required, err := s.Policy.Required(r.Currency, r.Amount())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	r.ID = r.ChargeID()
+	r.Required = required
+	r.Approvals = []Approval{}
+	r.Status = StatusPending
+	r.CreatedAt = now
+	r.ExpiresAt = now.Add(ttl)
+
+	if err := s.Store.Create(ctx, r); err != nil {
+		return nil, err
+	}
+	detail := fmt.Sprintf("%.2f %s, %d approvals required", r.Amount(), r.Currency, r.Required)
+	if err := s.audit(ctx, r.ID, ActionRequested, r.Requester, detail); err != nil {
+		return nil, err
+	}
+
+	if r.Required == 0 {
+		return r, s.approve(ctx, r)
+	}
+	return r, nil
+}
+
+// Approve records an approval of a pending request. The requester and
+// previous approvers cannot approve. Once the request has the approvals
+// it needs, it is forwarded; a forwarding error is returned but the
+// request stays approved so that Forward can retry it.
+func (s *Service) Approve(ctx context.Context, id, approver string) (*Request, error) {
+	r, err := s.pending(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(approver, r.Requester) {
+		return nil, fmt.Errorf("%w: %s", ErrSelfApproval, id)
+	}
+	for _, a := range r.Approvals {
+		if strings.EqualFold(a.By, approver) {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateApproval, id)
+		}
+	}
+
+	r.Approvals = append(r.Approvals, Approval{By: approver, At: time.Now().UTC()})
+	complete := len(r.Approvals) >= r.Required
+	if complete {
+		r.Status = StatusApproved
+	}
+	if err := s.Store.Update(ctx, r); err != nil {
+		return nil, err
+	}
+	detail := fmt.Sprintf("%d of %d approvals", len(r.Approvals), r.Required)
+	if err := s.audit(ctx, r.ID, ActionApproved, approver, detail); err != nil {
+		return nil, err
+	}
+
+	if !complete {
+		return r, nil
+	}
+	return r, s.forward(ctx, r)
+}
+
+// Reject rejects a pending request. The requester may withdraw their
+// own request this way.
+func (s *Service) Reject(ctx context.Context, id, approver, reason string) (*Request, error) {
+	r, err := s.pending(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Status = StatusRejected
+	if err := s.Store.Update(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, s.audit(ctx, r.ID, ActionRejected, approver, reason)
+}
+
+// pending returns the request with the given ID if it is still pending,
+// expiring it first if it is past its expiry.
+func (s *Service) pending(ctx context.Context, id, actor string) (*Request, error) {
+	if actor == "" {
+		return nil, fmt.Errorf("approval: approver is required")
+	}
+	r, err := s.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != StatusPending {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotPending, id, r.Status)
+	}
+	if time.Now().After(r.ExpiresAt) {
+		if err := s.expire(ctx, r); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrExpired, id)
+	}
+	return r, nil
+}
+
+// approve marks r approved and forwards it.
+func (s *Service) approve(ctx context.Context, r *Request) error {
+	r.Status = StatusApproved
+	if err := s.Store.Update(ctx, r); err != nil {
+		return err
+	}
+	return s.forward(ctx, r)
+}
+
+// Forward forwards an approved request whose earlier forward failed.
+// If another caller is forwarding it at the same time, one of them
+// fails with ErrConflict.
+func (s *Service) Forward(ctx context.Context, id string) (*Request, error) {
+	r, err := s.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status == StatusForwarding {
+		return nil, fmt.Errorf("%w: %s is being forwarded", ErrConflict, id)
+	}
+	if r.Status != StatusApproved {
+		return nil, fmt.Errorf("approval: %s is %s, not approved", id, r.Status)
+	}
+	return r, s.forward(ctx, r)
+}
+
+// forward claims an approved request by saving it as forwarding, passes
+// it to the Forwarder and records the outcome. A failed forward returns
+// the request to approved so that Forward can retry it.
+func (s *Service) forward(ctx context.Context, r *Request) error {
+	r.Status = StatusForwarding
+	if err := s.Store.Update(ctx, r); err != nil {
+		r.Status = StatusApproved
+		return err
+	}
+
+	if err := s.Forwarder.Forward(ctx, r); err != nil {
+		r.Status = StatusApproved
+		r.LastError = err.Error()
+		if updateErr := s.Store.Update(ctx, r); updateErr != nil {
+			return fmt.Errorf("%w (and failed to save: %v)", err, updateErr)
+		}
+		if auditErr := s.audit(ctx, r.ID, ActionForwardFailed, "system", err.Error()); auditErr != nil {
+			return fmt.Errorf("%w (and failed to audit: %v)", err, auditErr)
+		}
+		return err
+	}
+
+	r.Status = StatusForwarded
+	r.LastError = ""
+	if err := s.Store.Update(ctx, r); err != nil {
+		return err
+	}
+	return s.audit(ctx, r.ID, ActionForwarded, "system", "")
+}
+
+// ExpireStale expires every pending request past its expiry
+// and returns how many were expired.
+func (s *Service) ExpireStale(ctx context.Context) (int, error) {
+	requests, err := s.Store.List(ctx, StatusPending)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	now := time.Now()
+	for _, r := range requests {
+		if now.After(r.ExpiresAt) {
+			if err := s.expire(ctx, r); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// expire marks r expired.
+func (s *Service) expire(ctx context.Context, r *Request) error {
+	r.Status = StatusExpired
+	if err := s.Store.Update(ctx, r); err != nil {
+		return err
+	}
+	return s.audit(ctx, r.ID, ActionExpired, "system", "")
+}
+
+// Audit returns the audit trail of a request after checking that it is intact.
+func (s *Service) Audit(ctx context.Context, id string) ([]AuditEntry, error) {
+	entries, err := s.Store.Audit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return entries, VerifyAudit(entries)
+}
+
+// audit appends an entry to the audit trail of a request.
+func (s *Service) audit(ctx context.Context, id, action, actor, detail string) error {
+	entries, err := s.Store.Audit(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	entry := AuditEntry{
+		RequestID: id,
+		Seq:       len(entries) + 1,
+		Action:    action,
+		Actor:     actor,
+		Detail:    detail,
+		At:        time.Now().UTC(),
+	}
+	if len(entries) > 0 {
+		entry.PrevHash = entries[len(entries)-1].Hash
+	}
+	entry.Hash = entry.hash()
+	return s.Store.Append(ctx, entry)
+}
@@ -0,0 +1,214 @@
+package approval
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/internal/sqlutil"
+)
+
+// SQLStore is a Store backed by a database/sql database, such as
+// SQLite (e.g. with modernc.org/sqlite) or PostgreSQL (e.g. with pgx).
+// The caller opens db with the driver of their choice and passes the
+// matching dialect.
+//
+// Audit entries are only ever inserted, keyed by request and sequence
+// number, so two writers cannot both append the same entry. Grant the
+// application INSERT and SELECT, but not UPDATE or DELETE, on the audit
+// table to keep it append-only; VerifyAudit detects entries altered or
+// removed by other means.
+type SQLStore struct {
+	db      *sql.DB
+	dialect paychangu.SQLDialect
+
+	// Table is the name of the table requests are kept in, and the
+	// prefix of the table the audit trail is kept in. It defaults to
+	// "paychangu_approvals".
+	Table string
+}
+
+// NewSQLStore creates an SQLStore using db.
+func NewSQLStore(db *sql.DB, dialect paychangu.SQLDialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect, Table: "paychangu_approvals"}
+}
+
+// audit returns the name of the audit table.
+func (s *SQLStore) audit() string {
+	return s.Table + "_audit"
+}
+
+// CreateTable creates the tables requests and the audit trail
+// are kept in, if they do not exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + s.Table + ` (
+	id VARCHAR(255) PRIMARY KEY,
+	status VARCHAR(32) NOT NULL,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + s.Table + `_status ON ` + s.Table + ` (status)`,
+		`CREATE TABLE IF NOT EXISTS ` + s.audit() + ` (
+	request_id VARCHAR(255) NOT NULL,
+	seq INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (request_id, seq)
+)`,
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create implements Store.
+func (s *SQLStore) Create(ctx context.Context, r *Request) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+s.Table+`
+	(id, status, version, data, created_at) VALUES (?, ?, ?, ?, ?)`),
+		r.ID, r.Status, r.Version, string(data), r.CreatedAt)
+	return err
+}
+
+// Get implements Store.
+func (s *SQLStore) Get(ctx context.Context, id string) (*Request, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT data FROM `+s.Table+` WHERE id = ?`), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Request
+	return &r, json.Unmarshal([]byte(data), &r)
+}
+
+// Update implements Store.
+func (s *SQLStore) Update(ctx context.Context, r *Request) error {
+	r.Version++
+	data, err := json.Marshal(r)
+	if err != nil {
+		r.Version--
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.Table+`
+	SET status = ?, version = ?, data = ? WHERE id = ? AND version = ?`),
+		r.Status, r.Version, string(data), r.ID, r.Version-1)
+	if err != nil {
+		r.Version--
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		r.Version--
+		if _, err := s.Get(ctx, r.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrConflict, r.ID)
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *SQLStore) List(ctx context.Context, statuses ...Status) ([]*Request, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT data FROM `+s.Table+`
+	WHERE status IN (`+placeholders+`) ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*Request
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var r Request
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, err
+		}
+		requests = append(requests, &r)
+	}
+	return requests, rows.Err()
+}
+
+// Append implements Store. It fails with ErrConflict if the entry's
+// sequence number does not follow the last one of its request.
+func (s *SQLStore) Append(ctx context.Context, entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	var last int
+	err = s.db.QueryRowContext(ctx, s.rebind(`SELECT COALESCE(MAX(seq), 0) FROM `+s.audit()+` WHERE request_id = ?`), entry.RequestID).Scan(&last)
+	if err != nil {
+		return err
+	}
+	if entry.Seq != last+1 {
+		return fmt.Errorf("%w: audit of %s", ErrConflict, entry.RequestID)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+s.audit()+`
+	(request_id, seq, data) VALUES (?, ?, ?)`), entry.RequestID, entry.Seq, string(data))
+	if err != nil {
+		// Another writer may have appended the same sequence number since we looked.
+		var taken int
+		if s.db.QueryRowContext(ctx, s.rebind(`SELECT seq FROM `+s.audit()+` WHERE request_id = ? AND seq = ?`), entry.RequestID, entry.Seq).Scan(&taken) == nil {
+			return fmt.Errorf("%w: audit of %s", ErrConflict, entry.RequestID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Audit implements Store.
+func (s *SQLStore) Audit(ctx context.Context, requestID string) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT data FROM `+s.audit()+`
+	WHERE request_id = ? ORDER BY seq`), requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// rebind rewrites the ? placeholders in query for s's dialect.
+func (s *SQLStore) rebind(query string) string {
+	return sqlutil.Rebind(query, s.dialect == paychangu.DialectDollar)
+}
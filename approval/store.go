@@ -0,0 +1,147 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Store persists requests and their audit trail.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Create saves a new request.
+	Create(ctx context.Context, r *Request) error
+
+	// Get returns the request with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Request, error)
+
+	// Update saves r if its Version matches the stored one, and
+	// increments r.Version. Otherwise it returns ErrConflict.
+	Update(ctx context.Context, r *Request) error
+
+	// List returns the requests with any of the given statuses, oldest first.
+	List(ctx context.Context, statuses ...Status) ([]*Request, error)
+
+	// Append adds an entry to the audit trail of its request.
+	// Entries are never changed or removed.
+	Append(ctx context.Context, entry AuditEntry) error
+
+	// Audit returns the audit trail of a request, oldest first.
+	Audit(ctx context.Context, requestID string) ([]AuditEntry, error)
+}
+
+// MemoryStore is a Store that keeps requests in memory, for tests
+// and single-process use. Everything is lost when the process exits.
+type MemoryStore struct {
+	mu       sync.Mutex
+	requests map[string][]byte
+	audit    map[string][]AuditEntry
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{requests: make(map[string][]byte), audit: make(map[string][]AuditEntry)}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(ctx context.Context, r *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.requests[r.ID]; ok {
+		return fmt.Errorf("request %s already exists", r.ID)
+	}
+	return s.put(r)
+}
+
+// put stores a copy of r. s.mu must be held.
+func (s *MemoryStore) put(r *Request) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.requests[r.ID] = data
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.requests[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	var r Request
+	return &r, json.Unmarshal(data, &r)
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(ctx context.Context, r *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.requests[r.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, r.ID)
+	}
+	var stored Request
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored.Version != r.Version {
+		return fmt.Errorf("%w: %s", ErrConflict, r.ID)
+	}
+
+	r.Version++
+	if err := s.put(r); err != nil {
+		r.Version--
+		return err
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List(ctx context.Context, statuses ...Status) ([]*Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []*Request
+	for _, data := range s.requests {
+		var r Request
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, err
+		}
+		for _, status := range statuses {
+			if r.Status == status {
+				requests = append(requests, &r)
+				break
+			}
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.Before(requests[j].CreatedAt) })
+	return requests, nil
+}
+
+// Append implements Store.
+func (s *MemoryStore) Append(ctx context.Context, entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n := len(s.audit[entry.RequestID]); entry.Seq != n+1 {
+		return fmt.Errorf("%w: audit of %s", ErrConflict, entry.RequestID)
+	}
+	s.audit[entry.RequestID] = append(s.audit[entry.RequestID], entry)
+	return nil
+}
+
+// Audit implements Store.
+func (s *MemoryStore) Audit(ctx context.Context, requestID string) ([]AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]AuditEntry(nil), s.audit[requestID]...), nil
+}
@@ -0,0 +1,283 @@
+package paychangu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped, when a call is rejected because
+// the circuit breaker for its operation class is open. Use errors.Is
+// to detect it.
+var ErrCircuitOpen = errors.New("paychangu: circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+// Circuit breaker states.
+const (
+	// StateClosed lets calls through and counts failures.
+	StateClosed CircuitState = iota
+
+	// StateOpen rejects calls with ErrCircuitOpen until the cooldown ends.
+	StateOpen
+
+	// StateHalfOpen lets a limited number of trial calls through.
+	// A successful trial closes the circuit, a failed one opens it again.
+	StateHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// BreakerSettings configures a CircuitBreaker.
+type BreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures
+	// that open the circuit. It defaults to 5.
+	FailureThreshold int
+
+	// Cooldown is how long the circuit stays open before
+	// allowing trial calls. It defaults to 30 seconds.
+	Cooldown time.Duration
+
+	// HalfOpenMaxCalls is the number of trial calls allowed
+	// at once while half-open. It defaults to 1.
+	HalfOpenMaxCalls int
+
+	// IsFailure reports whether the outcome of a call counts as a
+	// failure. By default transport errors and 5xx responses do.
+	// Calls cancelled by their own context, or failing once its
+	// deadline has passed, are not recorded either way and do not
+	// reach IsFailure.
+	IsFailure func(resp *http.Response, err error) bool
+
+	// OnStateChange, if set, is called whenever the circuit
+	// of an operation class changes state, e.g. for alerting.
+	// It is called without the breaker's lock held, but should
+	// return quickly as the call it was triggered by waits for it.
+	OnStateChange func(class OperationClass, from, to CircuitState)
+}
+
+// CircuitBreaker fails calls fast while PayChangu is degraded. It keeps
+// a separate circuit per operation class, so failing payouts do not
+// stop payment verifications. It is safe for concurrent use.
+//
+// Example Usage:
+//
+//	breaker := paychangu.NewCircuitBreaker(paychangu.BreakerSettings{
+//	    FailureThreshold: 5,
+//	    Cooldown:         time.Minute,
+//	    OnStateChange: func(class paychangu.OperationClass, from, to paychangu.CircuitState) {
+//	        log.Printf("paychangu %s circuit: %s -> %s", class, from, to)
+//	    },
+//	})
+//	client := paychangu.New("your_secret_key", paychangu.WithCircuitBreaker(breaker))
+//
+//	_, err := client.VerifyPayment("TX-123456")
+//	if errors.Is(err, paychangu.ErrCircuitOpen) {
+//	    // PayChangu is degraded, try again later
+//	}
+type CircuitBreaker struct {
+	settings BreakerSettings
+
+	mu       sync.Mutex
+	circuits map[OperationClass]*circuit
+}
+
+// circuit holds the state of one operation class.
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trials   int
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with the given settings.
+func NewCircuitBreaker(settings BreakerSettings) *CircuitBreaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 5
+	}
+	if settings.Cooldown <= 0 {
+		settings.Cooldown = 30 * time.Second
+	}
+	if settings.HalfOpenMaxCalls <= 0 {
+		settings.HalfOpenMaxCalls = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = isFailure
+	}
+	return &CircuitBreaker{settings: settings, circuits: make(map[OperationClass]*circuit)}
+}
+
+// WithCircuitBreaker rejects calls with ErrCircuitOpen while the
+// breaker's circuit for their operation class is open. The breaker
+// sees each call once, after any retries.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(p *payChangu) {
+		p.breaker = breaker
+	}
+}
+
+// isFailure is the default BreakerSettings.IsFailure.
+func isFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// State returns the current state of the circuit for class.
+func (b *CircuitBreaker) State(class OperationClass) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(class)
+	if c.state == StateOpen && time.Since(c.openedAt) >= b.settings.Cooldown {
+		return StateHalfOpen
+	}
+	return c.state
+}
+
+// circuit returns the circuit for class, creating it if needed.
+// b.mu must be held.
+func (b *CircuitBreaker) circuit(class OperationClass) *circuit {
+	c, ok := b.circuits[class]
+	if !ok {
+		c = &circuit{}
+		b.circuits[class] = c
+	}
+	return c
+}
+
+// setState moves c to state. It returns the function that reports the
+// change, to be called once b.mu is released, or nil if there is none.
+// b.mu must be held.
+func (b *CircuitBreaker) setState(class OperationClass, c *circuit, state CircuitState) func() {
+	if c.state == state {
+		return nil
+	}
+	from := c.state
+	c.state = state
+	c.trials = 0
+	if state == StateOpen {
+		c.openedAt = time.Now()
+	}
+	if state == StateClosed {
+		c.failures = 0
+	}
+	if b.settings.OnStateChange == nil {
+		return nil
+	}
+	return func() { b.settings.OnStateChange(class, from, state) }
+}
+
+// notify calls the state change reporter returned by setState, if any.
+func notify(changed func()) {
+	if changed != nil {
+		changed()
+	}
+}
+
+// allow reports whether a call of class may proceed. It also returns
+// when the circuit was last opened, which identifies the half-open
+// period a trial call belongs to.
+func (b *CircuitBreaker) allow(class OperationClass) (bool, time.Time) {
+	var changed func()
+	defer func() { notify(changed) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(class)
+	if c.state == StateOpen {
+		if time.Since(c.openedAt) < b.settings.Cooldown {
+			return false, c.openedAt
+		}
+		changed = b.setState(class, c, StateHalfOpen)
+	}
+	if c.state == StateHalfOpen {
+		if c.trials >= b.settings.HalfOpenMaxCalls {
+			return false, c.openedAt
+		}
+		c.trials++
+	}
+	return true, c.openedAt
+}
+
+// record updates the circuit of class with the outcome of a call.
+func (b *CircuitBreaker) record(class OperationClass, failed bool) {
+	var changed func()
+	defer func() { notify(changed) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(class)
+	switch {
+	case c.state == StateHalfOpen && failed:
+		changed = b.setState(class, c, StateOpen)
+	case c.state == StateHalfOpen:
+		changed = b.setState(class, c, StateClosed)
+	case failed:
+		c.failures++
+		if c.failures >= b.settings.FailureThreshold {
+			changed = b.setState(class, c, StateOpen)
+		}
+	default:
+		c.failures = 0
+	}
+}
+
+// release gives back the trial slot taken by a call of class whose
+// outcome is not recorded, so that a cancelled trial does not leave
+// the circuit half-open with no slot for the next one. openedAt is
+// the time returned by allow for the call.
+func (b *CircuitBreaker) release(class OperationClass, openedAt time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(class)
+	if c.state == StateHalfOpen && c.openedAt.Equal(openedAt) && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// Middleware returns the Middleware that applies the breaker.
+// WithCircuitBreaker installs it outside the retries; use this
+// directly with WithMiddleware to place it elsewhere.
+func (b *CircuitBreaker) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op, _ := OperationFromContext(req.Context())
+			class := op.Class()
+			ok, openedAt := b.allow(class)
+			if !ok {
+				return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, class)
+			}
+
+			resp, err := next.Do(req)
+			if err != nil && (errors.Is(err, context.Canceled) || req.Context().Err() != nil) {
+				// The caller gave up or ran out of time, which says
+				// nothing about PayChangu.
+				b.release(class, openedAt)
+				return resp, err
+			}
+			b.record(class, b.settings.IsFailure(resp, err))
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package paychangu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBreakerIgnoresCallerDeadline(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerSettings{FailureThreshold: 1})
+	doer := breaker.Middleware()(DoerFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.paychangu.com/verify-payment/TX-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doer.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if state := breaker.State(ClassLookups); state != StateClosed {
+		t.Errorf("state = %s after the caller's deadline, want closed", state)
+	}
+
+	// A timeout of PayChangu's own still counts.
+	doer = breaker.Middleware()(DoerFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, context.DeadlineExceeded
+	}))
+	req, err = http.NewRequest(http.MethodGet, "https://api.paychangu.com/verify-payment/TX-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doer.Do(req)
+	if state := breaker.State(ClassLookups); state != StateOpen {
+		t.Errorf("state = %s after a timeout, want open", state)
+	}
+}
@@ -0,0 +1,187 @@
+package paychangu
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NewPaymentRequest starts a payment Request for the given amount and
+// currency, with a TxRef made by NewReference("TX"). The With methods
+// fill in the remaining fields and can be chained, each returning an
+// updated copy of the request.
+//
+// Example Usage:
+//
+//	request := paychangu.NewPaymentRequest(10500, "MWK").
+//	    WithCustomer(paychangu.CustomerInfo{FirstName: "John", LastName: "Doe", Email: "john@example.com"}).
+//	    WithCustomization("Order Payment", "Payment for electronics").
+//	    WithCallbacks("https://yourapp.com/success", "https://yourapp.com/failure")
+//	if err := request.Validate(); err != nil {
+//	    log.Fatalf("Invalid payment request: %v", err)
+//	}
+//	resp, err := client.InitiatePayment(request)
+func NewPaymentRequest(amount float32, currency string) Request {
+	return Request{Amount: amount, Currency: currency, TxRef: NewReference("TX")}
+}
+
+// WithCustomer sets the customer details. When customer.ID is set
+// the payment is also linked to that stored customer.
+func (r Request) WithCustomer(customer CustomerInfo) Request {
+	r.CustomerID = customer.ID
+	r.Email = customer.Email
+	r.FirstName = customer.FirstName
+	r.LastName = customer.LastName
+	return r
+}
+
+// WithCustomization sets the title and description shown on the checkout page.
+func (r Request) WithCustomization(title, description string) Request {
+	r.Customization.Title = title
+	r.Customization.Description = description
+	return r
+}
+
+// WithCallbacks sets the URLs the customer is redirected to
+// after a successful and a failed payment respectively.
+func (r Request) WithCallbacks(callbackURL, returnURL string) Request {
+	r.CallbackURL = callbackURL
+	r.ReturnURL = returnURL
+	return r
+}
+
+// WithTxRef replaces the generated transaction reference,
+// e.g. with one from a ReferenceGenerator carrying tenant tags.
+func (r Request) WithTxRef(txRef string) Request {
+	r.TxRef = txRef
+	return r
+}
+
+// WithMeta sets the metadata sent with the payment. See DecodeMeta.
+func (r Request) WithMeta(meta any) Request {
+	r.Meta = meta
+	return r
+}
+
+// WithSplits adds settlement splits to sub-accounts.
+func (r Request) WithSplits(splits ...Split) Request {
+	r.Splits = append(append([]Split(nil), r.Splits...), splits...)
+	return r
+}
+
+// Validate reports the required fields that are missing or invalid
+// on the request, so mistakes surface before calling the API.
+func (r Request) Validate() error {
+	var errs []error
+
+	if r.Amount <= 0 {
+		errs = append(errs, errors.New("amount must be greater than zero"))
+	}
+	if r.Currency == "" {
+		errs = append(errs, errors.New("currency is required"))
+	}
+	if r.FirstName == "" && r.CustomerID == "" {
+		errs = append(errs, errors.New("first name or customer ID is required"))
+	}
+	if r.CallbackURL == "" {
+		errs = append(errs, errors.New("callback URL is required"))
+	}
+	if r.ReturnURL == "" {
+		errs = append(errs, errors.New("return URL is required"))
+	}
+	if r.TxRef == "" {
+		errs = append(errs, errors.New("tx_ref is required"))
+	}
+	if r.Customization.Title == "" {
+		errs = append(errs, errors.New("customization title is required"))
+	}
+	if r.Customization.Description == "" {
+		errs = append(errs, errors.New("customization description is required"))
+	}
+
+	var percent float64
+	for i, split := range r.Splits {
+		if split.SubAccountID == "" {
+			errs = append(errs, fmt.Errorf("split %d: sub-account ID is required", i))
+		}
+		switch split.Type {
+		case SplitTypePercentage:
+			percent += split.Value
+		case SplitTypeFlat:
+		default:
+			errs = append(errs, fmt.Errorf("split %d: unknown split type %q", i, split.Type))
+		}
+		if split.Value <= 0 {
+			errs = append(errs, fmt.Errorf("split %d: value must be greater than zero", i))
+		}
+	}
+	if percent > 100 {
+		errs = append(errs, fmt.Errorf("split percentages add up to %g, more than 100", percent))
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewMobileMoneyPayoutRequest starts a MobileMoneyPayoutRequest paying amount
+// to mobile through the operator with the given ref ID, with a ChargeID made
+// by NewReference("PAYOUT").
+//
+// Example Usage:
+//
+//	request := paychangu.NewMobileMoneyPayoutRequest("0999123456", operatorRefID, 5000).
+//	    WithRecipient("John", "Doe", "john@example.com")
+//	resp, err := client.InitiateMobileMoneyPayout(request)
+func NewMobileMoneyPayoutRequest(mobile, operatorRefID string, amount float64) MobileMoneyPayoutRequest {
+	return MobileMoneyPayoutRequest{
+		Mobile:                   mobile,
+		MobileMoneyOperatorRefID: operatorRefID,
+		Amount:                   amount,
+		ChargeID:                 NewReference("PAYOUT"),
+	}
+}
+
+// WithChargeID replaces the generated charge ID.
+func (r MobileMoneyPayoutRequest) WithChargeID(chargeID string) MobileMoneyPayoutRequest {
+	r.ChargeID = chargeID
+	return r
+}
+
+// WithRecipient sets the optional recipient details.
+func (r MobileMoneyPayoutRequest) WithRecipient(firstName, lastName, email string) MobileMoneyPayoutRequest {
+	r.FirstName = firstName
+	r.LastName = lastName
+	r.Email = email
+	return r
+}
+
+// NewBankPayoutRequest starts a BankPayoutRequest paying amount to the
+// given account at the bank with bankUUID, with a ChargeID made by
+// NewReference("PAYOUT").
+//
+// Example Usage:
+//
+//	request := paychangu.NewBankPayoutRequest(bankUUID, "John Doe", "1234567890", 25000)
+//	resp, err := client.InitiateBankPayout(request)
+func NewBankPayoutRequest(bankUUID, accountName, accountNumber string, amount float64) BankPayoutRequest {
+	return BankPayoutRequest{
+		PayoutMethod:      "bank_transfer",
+		BankUUID:          bankUUID,
+		Amount:            amount,
+		ChargeID:          NewReference("PAYOUT"),
+		BankAccountName:   accountName,
+		BankAccountNumber: accountNumber,
+	}
+}
+
+// WithChargeID replaces the generated charge ID.
+func (r BankPayoutRequest) WithChargeID(chargeID string) BankPayoutRequest {
+	r.ChargeID = chargeID
+	return r
+}
+
+// WithRecipient sets the optional recipient details.
+func (r BankPayoutRequest) WithRecipient(firstName, lastName, email string) BankPayoutRequest {
+	r.FirstName = firstName
+	r.LastName = lastName
+	r.Email = email
+	return r
+}
@@ -0,0 +1,106 @@
+package paychangu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultBaseURL is the PayChangu API
+// endpoint used unless WithBaseURL is given.
+const defaultBaseURL = "https://api.paychangu.com"
+
+// An Option configures optional behaviour of the client created by New.
+type Option func(*payChangu)
+
+// WithHTTPClient sets the Doer, usually an *http.Client, that sends
+// requests to the API once they have passed through the middlewares.
+// By default a single http.Client is shared by all calls.
+func WithHTTPClient(client Doer) Option {
+	return func(p *payChangu) {
+		p.httpClient = client
+	}
+}
+
+// WithBaseURL sends requests to baseURL instead of
+// https://api.paychangu.com, for example a local mock server.
+func WithBaseURL(baseURL string) Option {
+	return func(p *payChangu) {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithRetries retries failed GET requests up to max times.
+// It is shorthand for adding RetryMiddleware(max) after any
+// middlewares given with WithMiddleware.
+func WithRetries(max int) Option {
+	return func(p *payChangu) {
+		p.maxRetries = max
+	}
+}
+
+// call sends a request for op to the API and decodes the JSON response
+// into out. body, if not nil, is sent as JSON. Responses with a non-2xx
+// status are returned as an *APIError.
+func (p *payChangu) call(ctx context.Context, op Operation, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.do(req, op)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp.StatusCode, data)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// do sends req on behalf of op through the middlewares.
+func (p *payChangu) do(req *http.Request, op Operation) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), operationKey{}, op)
+	ctx = context.WithValue(ctx, statsKey{}, &CallStats{})
+	return p.doer.Do(req.WithContext(ctx))
+}
+
+// send is the innermost Doer, which hands req to the HTTP client.
+func (p *payChangu) send(req *http.Request) (*http.Response, error) {
+	resp, err := p.httpClient.Do(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		callStats(req.Context()).RateLimited++
+	}
+	return resp, err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strconv"
+)
+
+// operatorsList prints the supported mobile money operators.
+func operatorsList(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("operators list")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	operators, err := c.GetMobileMoneyOperatorsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"NAME", "REF_ID", "SHORT_CODE", "COUNTRY", "CURRENCY", "WITHDRAWALS"}}
+	for _, op := range operators {
+		t.rows = append(t.rows, []string{
+			op.Name,
+			op.RefID,
+			op.ShortCode,
+			op.SupportedCountry.Name,
+			op.SupportedCountry.Currency,
+			strconv.FormatBool(op.SupportsWithdrawals),
+		})
+	}
+	return write(out, opts.output, operators, t)
+}
+
+// banksList prints the banks supported for payouts in a currency.
+func banksList(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("banks list")
+	currency := fs.String("currency", "MWK", "currency code")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	banks, err := c.GetSupportedBanksContext(ctx, *currency)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"NAME", "UUID"}}
+	for _, bank := range banks {
+		t.rows = append(t.rows, []string{bank.Name, bank.UUID})
+	}
+	return write(out, opts.output, banks, t)
+}
@@ -0,0 +1,206 @@
+// Command paychangu calls the PayChangu API from the command line,
+// for operations staff checking payments and payouts.
+//
+// Usage:
+//
+//	paychangu pay init --amount 10500 --currency MWK --first-name Jane ...
+//	paychangu pay verify <tx_ref>
+//	paychangu operators list
+//	paychangu banks list --currency MWK
+//	paychangu payout mobile --mobile 0881234567 --operator <ref_id> --amount 5000
+//	paychangu payout bank --bank <uuid> --account-name "Jane Doe" --account-number 1000000010 --amount 50000
+//	paychangu payout status [--bank] <charge_id>
+//	paychangu webhook listen [--addr localhost:4242] [--forward URL] [--dir webhooks]
+//	paychangu webhook replay [--forward URL] <file>
+//	paychangu webhook trigger payment.success [--tx-ref TX-...] [--forward URL]
+//
+// Every command accepts -o table|json|csv to choose the output format
+// and --profile to choose a configuration profile.
+//
+// The secret key and other settings are read like paychangu.NewFromEnv
+// does, from PAYCHANGU_SECRET_KEY and the other PAYCHANGU_* variables.
+// A profile named with --profile or PAYCHANGU_PROFILE is read from
+// $XDG_CONFIG_HOME/paychangu/<profile>.env (~/.config/paychangu on
+// Linux), a file of KEY=VALUE lines using the same variable names.
+// Environment variables take precedence over the profile. A profile
+// cannot be combined with PAYCHANGU_CONFIG_FILE naming another file.
+//
+// The webhook commands help test webhook handlers locally. webhook listen
+// receives webhooks, prints them, saves them to a directory and forwards
+// them to a local URL. webhook replay sends a saved webhook again, and
+// webhook trigger sends a synthetic event signed with
+// PAYCHANGU_WEBHOOK_SECRET.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// command runs a subcommand with the arguments following its name.
+type command func(ctx context.Context, args []string, out io.Writer) error
+
+// commands maps command and subcommand names to their implementation.
+var commands = map[string]map[string]command{
+	"pay": {
+		"init":   payInit,
+		"verify": payVerify,
+	},
+	"operators": {
+		"list": operatorsList,
+	},
+	"banks": {
+		"list": banksList,
+	},
+	"payout": {
+		"mobile": payoutMobile,
+		"bank":   payoutBank,
+		"status": payoutStatus,
+	},
+	"webhook": {
+		"listen":  webhookListen,
+		"replay":  webhookReplay,
+		"trigger": webhookTrigger,
+	},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run dispatches args to the matching command.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		usage(os.Stderr)
+		return flag.ErrHelp
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		usage(os.Stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	cmd, ok := subcommands[args[1]]
+	if !ok {
+		usage(os.Stderr)
+		return fmt.Errorf("unknown command %q", args[0]+" "+args[1])
+	}
+	return cmd(ctx, args[2:], out)
+}
+
+// usage lists the available commands.
+func usage(w io.Writer) {
+	var names []string
+	for name, subcommands := range commands {
+		for sub := range subcommands {
+			names = append(names, name+" "+sub)
+		}
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "Usage: paychangu <command> [flags] [args]\n\nCommands:\n  %s\n", strings.Join(names, "\n  "))
+}
+
+// client is the part of the PayChangu client used by the commands.
+type client interface {
+	InitiatePaymentContext(ctx context.Context, request paychangu.Request) (*paychangu.Response, error)
+	VerifyPaymentContext(ctx context.Context, txRef string) (*paychangu.VerifyPaymentResponse, error)
+	GetMobileMoneyOperatorsContext(ctx context.Context) ([]paychangu.MobileMoneyOperator, error)
+	GetSupportedBanksContext(ctx context.Context, currency string) ([]paychangu.Bank, error)
+	InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error)
+	InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error)
+	GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error)
+	GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error)
+}
+
+// options holds the flags shared by every command.
+type options struct {
+	output  string
+	profile string
+}
+
+// newFlagSet returns a flag set for the named command
+// with the shared flags already defined. The output format is checked
+// as it is parsed, so that a command never calls PayChangu only to fail
+// writing the result.
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet("paychangu "+name, flag.ContinueOnError)
+	opts := &options{output: "table"}
+	fs.Func("o", "output format: table, json or csv (default table)", func(format string) error {
+		switch format {
+		case "table", "json", "csv":
+			opts.output = format
+			return nil
+		default:
+			return fmt.Errorf("unknown output format %q", format)
+		}
+	})
+	fs.StringVar(&opts.profile, "profile", os.Getenv("PAYCHANGU_PROFILE"), "configuration profile")
+	return fs, opts
+}
+
+// parse parses args with fs, allowing flags to follow
+// positional arguments, and returns the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// config loads the configuration, reading the selected profile first.
+func (o *options) config() (paychangu.Config, error) {
+	if o.profile != "" {
+		path, err := profilePath(o.profile)
+		if err != nil {
+			return paychangu.Config{}, err
+		}
+		if file := os.Getenv(paychangu.EnvConfigFile); file != "" && file != path {
+			return paychangu.Config{}, fmt.Errorf("profile %q conflicts with %s=%s: unset one of them", o.profile, paychangu.EnvConfigFile, file)
+		}
+		os.Setenv(paychangu.EnvConfigFile, path)
+	}
+	return paychangu.LoadConfig()
+}
+
+// profilePath returns the path of the named profile.
+func profilePath(profile string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "paychangu", profile+".env"), nil
+}
+
+// client creates a client from the loaded configuration.
+func (o *options) client() (client, error) {
+	config, err := o.config()
+	if err != nil {
+		return nil, err
+	}
+	return paychangu.NewFromConfig(config)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// payInit starts a payment and prints its checkout URL.
+func payInit(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("pay init")
+	amountFlag := fs.Float64("amount", 0, "amount to collect (required)")
+	currency := fs.String("currency", "MWK", "currency code")
+	firstName := fs.String("first-name", "", "customer first name")
+	lastName := fs.String("last-name", "", "customer last name")
+	email := fs.String("email", "", "customer email")
+	customerID := fs.String("customer-id", "", "stored customer ID")
+	callbackURL := fs.String("callback-url", "", "URL after a successful payment (required)")
+	returnURL := fs.String("return-url", "", "URL after a failed payment (required)")
+	title := fs.String("title", "Payment", "checkout page title")
+	description := fs.String("description", "Payment", "checkout page description")
+	txRef := fs.String("tx-ref", "", "transaction reference (generated if empty)")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	request := paychangu.NewPaymentRequest(float32(*amountFlag), *currency).
+		WithCustomer(paychangu.CustomerInfo{ID: *customerID, FirstName: *firstName, LastName: *lastName, Email: *email}).
+		WithCustomization(*title, *description).
+		WithCallbacks(*callbackURL, *returnURL)
+	if *txRef != "" {
+		request = request.WithTxRef(*txRef)
+	}
+	if err := request.Validate(); err != nil {
+		return err
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.InitiatePaymentContext(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return write(out, opts.output, resp, table{
+		header: []string{"TX_REF", "AMOUNT", "CURRENCY", "STATUS", "CHECKOUT_URL"},
+		rows: [][]string{{
+			request.TxRef,
+			amount(resp.Data.Data.Amount),
+			resp.Data.Data.Currency,
+			resp.Data.Data.Status,
+			resp.Data.CheckoutURL,
+		}},
+	})
+}
+
+// payVerify prints the status of a payment.
+func payVerify(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("pay verify")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: paychangu pay verify <tx_ref>")
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.VerifyPaymentContext(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	d := resp.Data
+	return write(out, opts.output, resp, table{
+		header: []string{"TX_REF", "REFERENCE", "STATUS", "AMOUNT", "CURRENCY", "CHARGES", "ATTEMPTS", "MODE"},
+		rows: [][]string{{
+			d.TxRef,
+			d.Reference,
+			d.Status,
+			amount(d.Amount),
+			d.Currency,
+			amount(d.Charges),
+			strconv.Itoa(d.Attempts),
+			d.Mode,
+		}},
+	})
+}
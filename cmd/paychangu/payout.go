@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// payoutHeader is the table header of payout results.
+var payoutHeader = []string{"CHARGE_ID", "REF_ID", "STATUS", "AMOUNT", "CURRENCY", "RECIPIENT", "CREATED_AT"}
+
+// mobilePayoutRow returns the table row of a mobile money payout.
+func mobilePayoutRow(d paychangu.PayoutTransactionDetails) []string {
+	return []string{d.ChargeID, d.RefID, d.Status, amount(d.Amount), d.Currency, d.Mobile, timestamp(d.CreatedAt)}
+}
+
+// bankPayoutRow returns the table row of a bank payout.
+func bankPayoutRow(d paychangu.BankPayoutTransactionDetails) []string {
+	recipient := d.RecipientAccountDetails.BankName + " " + d.RecipientAccountDetails.AccountNumber
+	return []string{d.ChargeID, d.RefID, d.Status, amount(d.Amount), d.Currency, recipient, timestamp(d.CreatedAt)}
+}
+
+// timestamp formats t for table and CSV output.
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// payoutMobile sends a mobile money payout.
+func payoutMobile(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("payout mobile")
+	mobile := fs.String("mobile", "", "recipient phone number (required)")
+	operator := fs.String("operator", "", "operator ref ID from 'operators list' (required)")
+	amountFlag := fs.Float64("amount", 0, "amount to send (required)")
+	chargeID := fs.String("charge-id", "", "charge ID (generated if empty)")
+	firstName := fs.String("first-name", "", "recipient first name")
+	lastName := fs.String("last-name", "", "recipient last name")
+	email := fs.String("email", "", "recipient email")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *mobile == "" || *operator == "" || *amountFlag <= 0 {
+		return errors.New("--mobile, --operator and --amount are required")
+	}
+
+	request := paychangu.NewMobileMoneyPayoutRequest(*mobile, *operator, *amountFlag).
+		WithRecipient(*firstName, *lastName, *email)
+	if *chargeID != "" {
+		request = request.WithChargeID(*chargeID)
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.InitiateMobileMoneyPayoutContext(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return write(out, opts.output, resp, table{
+		header: payoutHeader,
+		rows:   [][]string{mobilePayoutRow(resp.Data.Transaction)},
+	})
+}
+
+// payoutBank sends a bank payout.
+func payoutBank(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("payout bank")
+	bank := fs.String("bank", "", "bank UUID from 'banks list' (required)")
+	accountName := fs.String("account-name", "", "recipient account name (required)")
+	accountNumber := fs.String("account-number", "", "recipient account number (required)")
+	amountFlag := fs.Float64("amount", 0, "amount to send (required)")
+	chargeID := fs.String("charge-id", "", "charge ID (generated if empty)")
+	firstName := fs.String("first-name", "", "recipient first name")
+	lastName := fs.String("last-name", "", "recipient last name")
+	email := fs.String("email", "", "recipient email")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *bank == "" || *accountName == "" || *accountNumber == "" || *amountFlag <= 0 {
+		return errors.New("--bank, --account-name, --account-number and --amount are required")
+	}
+
+	request := paychangu.NewBankPayoutRequest(*bank, *accountName, *accountNumber, *amountFlag).
+		WithRecipient(*firstName, *lastName, *email)
+	if *chargeID != "" {
+		request = request.WithChargeID(*chargeID)
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.InitiateBankPayoutContext(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return write(out, opts.output, resp, table{
+		header: payoutHeader,
+		rows:   [][]string{bankPayoutRow(resp.Data.Transaction)},
+	})
+}
+
+// payoutStatus prints the details of a payout.
+func payoutStatus(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("payout status")
+	isBank := fs.Bool("bank", false, "look up a bank payout instead of a mobile money payout")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: paychangu payout status [--bank] <charge_id>")
+	}
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	if *isBank {
+		details, err := c.GetBankPayoutDetailsContext(ctx, positional[0])
+		if err != nil {
+			return err
+		}
+		return write(out, opts.output, details, table{header: payoutHeader, rows: [][]string{bankPayoutRow(*details)}})
+	}
+
+	details, err := c.GetMobileMoneyPayoutDetailsContext(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return write(out, opts.output, details, table{header: payoutHeader, rows: [][]string{mobilePayoutRow(*details)}})
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// savedWebhook is the file format of webhooks saved by webhook listen.
+type savedWebhook struct {
+	ReceivedAt time.Time `json:"received_at"`
+	Signature  string    `json:"signature"`
+
+	// Payload is the body exactly as received, since
+	// reformatting it would invalidate the signature.
+	Payload string `json:"payload"`
+}
+
+// webhookListen runs a local webhook receiver that prints,
+// saves and optionally forwards every webhook it receives.
+func webhookListen(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("webhook listen")
+	addr := fs.String("addr", "localhost:4242", "address to listen on")
+	forwardURL := fs.String("forward", "", "local URL to forward webhooks to")
+	dir := fs.String("dir", "webhooks", "directory to save webhooks in, empty to not save")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	config, err := opts.config()
+	if err != nil {
+		return err
+	}
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	// Webhooks are handled concurrently, so each one's report is
+	// written in one piece while holding mu.
+	var mu sync.Mutex
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report bytes.Buffer
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			report.WriteTo(out)
+		}()
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		signature := r.Header.Get(paychangu.SignatureHeader)
+		received := time.Now()
+
+		verified := "not checked, PAYCHANGU_WEBHOOK_SECRET is not set"
+		if config.WebhookSecret != "" {
+			verified = "valid"
+			if err := paychangu.VerifyWebhookSignature(body, signature, config.WebhookSecret); err != nil {
+				verified = "INVALID"
+			}
+		}
+		fmt.Fprintf(&report, "--- %s %s %s (signature %s)\n", received.Format(time.RFC3339), r.Method, r.URL.Path, verified)
+		printPayload(&report, body)
+
+		if *dir != "" {
+			path, err := saveWebhook(*dir, received, body, signature)
+			if err != nil {
+				fmt.Fprintln(&report, "failed to save:", err)
+			} else {
+				fmt.Fprintln(&report, "saved to", path)
+			}
+		}
+
+		status := http.StatusOK
+		if *forwardURL != "" {
+			status, err = forward(r.Context(), *forwardURL, body, signature)
+			if err != nil {
+				fmt.Fprintln(&report, "failed to forward:", err)
+				status = http.StatusBadGateway
+			} else {
+				fmt.Fprintf(&report, "forwarded to %s: %d\n", *forwardURL, status)
+			}
+		}
+		w.WriteHeader(status)
+	})
+
+	server := &http.Server{Addr: *addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(out, "Listening for webhooks on http://%s\n", *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// printPayload pretty-prints a webhook body.
+func printPayload(out io.Writer, body []byte) {
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, body, "", "  ") != nil {
+		out.Write(body)
+		fmt.Fprintln(out)
+		return
+	}
+	pretty.WriteTo(out)
+	fmt.Fprintln(out)
+}
+
+// saveWebhook writes a webhook to dir and returns the path of the file.
+func saveWebhook(dir string, received time.Time, body []byte, signature string) (string, error) {
+	data, err := json.MarshalIndent(savedWebhook{ReceivedAt: received, Signature: signature, Payload: string(body)}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := received.UTC().Format("20060102T150405.000000000")
+	var event paychangu.WebhookEvent
+	if json.Unmarshal(body, &event) == nil && event.Ref() != "" {
+		name += "-" + strings.Map(func(r rune) rune {
+			if r == '/' || r == os.PathSeparator {
+				return '_'
+			}
+			return r
+		}, event.Ref())
+	}
+	path := filepath.Join(dir, name+".json")
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// forward posts a webhook body with its signature to url
+// and returns the response status.
+func forward(ctx context.Context, url string, body []byte, signature string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(paychangu.SignatureHeader, signature)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// webhookReplay sends a webhook saved by webhook listen again.
+func webhookReplay(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("webhook replay")
+	forwardURL := fs.String("forward", "http://localhost:8080/webhooks/paychangu", "URL to send the webhook to")
+	resign := fs.Bool("resign", false, "sign with PAYCHANGU_WEBHOOK_SECRET instead of the saved signature")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: paychangu webhook replay [--forward URL] <file>")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	var saved savedWebhook
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to read %s: %w", positional[0], err)
+	}
+
+	body, signature := []byte(saved.Payload), saved.Signature
+	if *resign {
+		config, err := opts.config()
+		if err != nil {
+			return err
+		}
+		if config.WebhookSecret == "" {
+			return fmt.Errorf("%s is not set", paychangu.EnvWebhookSecret)
+		}
+		signature = paychangu.SignWebhook(body, config.WebhookSecret)
+	}
+
+	status, err := forward(ctx, *forwardURL, body, signature)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "replayed %s to %s: %d\n", positional[0], *forwardURL, status)
+	return nil
+}
+
+// triggerEvents maps the events webhook trigger can send
+// to the event type and status of the synthetic webhook.
+var triggerEvents = map[string]struct{ eventType, status string }{
+	"payment.success": {"api.charge.payment", "success"},
+	"payment.failed":  {"api.charge.payment", "failed"},
+	"payout.success":  {"api.payout", "success"},
+	"payout.failed":   {"api.payout", "failed"},
+}
+
+// webhookTrigger sends a signed synthetic webhook.
+func webhookTrigger(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := newFlagSet("webhook trigger")
+	forwardURL := fs.String("forward", "http://localhost:8080/webhooks/paychangu", "URL to send the webhook to")
+	txRef := fs.String("tx-ref", "", "tx_ref of a payment event (generated if empty)")
+	chargeID := fs.String("charge-id", "", "charge_id of a payout event (generated if empty)")
+	amountFlag := fs.Float64("amount", 1000, "amount")
+	currency := fs.String("currency", "MWK", "currency code")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range triggerEvents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: paychangu webhook trigger <event> [flags]; events: %s", strings.Join(names, ", "))
+	}
+	kind, ok := triggerEvents[positional[0]]
+	if !ok {
+		return fmt.Errorf("unknown event %q; events: %s", positional[0], strings.Join(names, ", "))
+	}
+
+	config, err := opts.config()
+	if err != nil {
+		return err
+	}
+	if config.WebhookSecret == "" {
+		return fmt.Errorf("%s is not set", paychangu.EnvWebhookSecret)
+	}
+
+	event := paychangu.WebhookEvent{
+		EventType: kind.eventType,
+		Status:    kind.status,
+		Currency:  *currency,
+		Amount:    json.Number(amount(*amountFlag)),
+		Mode:      "test",
+		Reference: paychangu.NewReference(""),
+	}
+	if strings.HasPrefix(positional[0], "payout.") {
+		event.ChargeID = *chargeID
+		if event.ChargeID == "" {
+			event.ChargeID = paychangu.NewReference("PAYOUT")
+		}
+	} else {
+		event.TxRef = *txRef
+		if event.TxRef == "" {
+			event.TxRef = paychangu.NewReference("TX")
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	status, err := forward(ctx, *forwardURL, body, paychangu.SignWebhook(body, config.WebhookSecret))
+	if err != nil {
+		return err
+	}
+	printPayload(out, body)
+	fmt.Fprintf(out, "sent %s to %s: %d\n", positional[0], *forwardURL, status)
+	return nil
+}
@@ -0,0 +1,213 @@
+package paychangu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLiveKeyInNonProduction is returned by Config.Validate when a live
+// secret key is configured in a process not marked as production.
+var ErrLiveKeyInNonProduction = errors.New("paychangu: live secret key used outside production")
+
+// KeyMode tells test keys, which only move sandbox money, from live keys.
+type KeyMode string
+
+// Key modes.
+const (
+	// ModeTest is the mode of sandbox keys, which start with "SEC-TEST-".
+	ModeTest KeyMode = "test"
+
+	// ModeLive is the mode of every other key.
+	ModeLive KeyMode = "live"
+)
+
+// KeyModeOf returns the mode of secretKey.
+func KeyModeOf(secretKey string) KeyMode {
+	if strings.HasPrefix(strings.ToUpper(secretKey), "SEC-TEST-") {
+		return ModeTest
+	}
+	return ModeLive
+}
+
+// Environment variables read by LoadConfig.
+const (
+	EnvSecretKey     = "PAYCHANGU_SECRET_KEY"
+	EnvBaseURL       = "PAYCHANGU_BASE_URL"
+	EnvTimeout       = "PAYCHANGU_TIMEOUT"     // a time.Duration, e.g. "30s"
+	EnvMaxRetries    = "PAYCHANGU_MAX_RETRIES" // an integer
+	EnvWebhookSecret = "PAYCHANGU_WEBHOOK_SECRET"
+	EnvEnvironment   = "PAYCHANGU_ENV" // e.g. "production" or "staging"
+	EnvConfigFile    = "PAYCHANGU_CONFIG_FILE"
+)
+
+// Config holds the settings of a client, usually loaded by LoadConfig.
+type Config struct {
+	// SecretKey is the API secret key. It is required.
+	SecretKey string
+
+	// BaseURL overrides the API endpoint when set.
+	BaseURL string
+
+	// Timeout limits each HTTP request when greater than zero.
+	Timeout time.Duration
+
+	// MaxRetries is passed to WithRetries.
+	MaxRetries int
+
+	// WebhookSecret is the secret used to verify webhook signatures.
+	WebhookSecret string
+
+	// Environment names the deployment, e.g. "production" or "staging".
+	// Live keys are only accepted when it is "production" or "prod";
+	// when it is empty or names any other deployment, only test keys are.
+	Environment string
+}
+
+// LoadConfig reads a Config from the PAYCHANGU_* environment variables.
+// If PAYCHANGU_CONFIG_FILE is set, settings are first read from that file,
+// which holds one KEY=VALUE pair per line using the same variable names;
+// blank lines and lines starting with # are ignored. Environment variables
+// take precedence over the file.
+func LoadConfig() (Config, error) {
+	values := make(map[string]string)
+
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		if err := readConfigFile(path, values); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, name := range []string{EnvSecretKey, EnvBaseURL, EnvTimeout, EnvMaxRetries, EnvWebhookSecret, EnvEnvironment} {
+		if value, ok := os.LookupEnv(name); ok {
+			values[name] = value
+		}
+	}
+
+	config := Config{
+		SecretKey:     values[EnvSecretKey],
+		BaseURL:       values[EnvBaseURL],
+		WebhookSecret: values[EnvWebhookSecret],
+		Environment:   values[EnvEnvironment],
+	}
+
+	if value := values[EnvTimeout]; value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", EnvTimeout, err)
+		}
+		config.Timeout = timeout
+	}
+
+	if value := values[EnvMaxRetries]; value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", EnvMaxRetries, err)
+		}
+		config.MaxRetries = retries
+	}
+
+	return config, nil
+}
+
+// readConfigFile adds the KEY=VALUE pairs in the file at path to values.
+func readConfigFile(path string, values map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return scanner.Err()
+}
+
+// Mode returns the mode of the configured secret key.
+func (c Config) Mode() KeyMode {
+	return KeyModeOf(c.SecretKey)
+}
+
+// Production reports whether Environment marks the process as production.
+// An empty Environment does not.
+func (c Config) Production() bool {
+	switch strings.ToLower(strings.TrimSpace(c.Environment)) {
+	case "production", "prod":
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks that a secret key is set and that a live key is
+// only used in a process explicitly marked as production.
+func (c Config) Validate() error {
+	if c.SecretKey == "" {
+		return fmt.Errorf("paychangu: %s is not set", EnvSecretKey)
+	}
+	if c.Mode() == ModeLive && !c.Production() {
+		if strings.TrimSpace(c.Environment) == "" {
+			return fmt.Errorf("%w: %s is not set", ErrLiveKeyInNonProduction, EnvEnvironment)
+		}
+		return fmt.Errorf("%w: %s=%s", ErrLiveKeyInNonProduction, EnvEnvironment, c.Environment)
+	}
+	return nil
+}
+
+// Options returns the client options that apply c.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.BaseURL != "" {
+		opts = append(opts, WithBaseURL(c.BaseURL))
+	}
+	if c.Timeout > 0 {
+		opts = append(opts, WithHTTPClient(&http.Client{Timeout: c.Timeout}))
+	}
+	if c.MaxRetries > 0 {
+		opts = append(opts, WithRetries(c.MaxRetries))
+	}
+	return opts
+}
+
+// NewFromConfig validates config and creates a client from it.
+// opts are applied after the options derived from config.
+func NewFromConfig(config Config, opts ...Option) (*payChangu, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return New(config.SecretKey, append(config.Options(), opts...)...), nil
+}
+
+// NewFromEnv creates a client from the configuration returned by
+// LoadConfig. It fails if no secret key is set, or if a live key is
+// set without PAYCHANGU_ENV marking the process as production.
+//
+// Example Usage:
+//
+//	// PAYCHANGU_SECRET_KEY=SEC-TEST-... PAYCHANGU_ENV=staging
+//	client, err := paychangu.NewFromEnv(paychangu.WithLogger(logger))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewFromEnv(opts ...Option) (*payChangu, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(config, opts...)
+}
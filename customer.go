@@ -0,0 +1,169 @@
+package paychangu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// CreateCustomer stores a new customer profile with PayChangu. The returned
+// customer ID can be set on Request, MobileMoneyPayoutRequest and
+// BankPayoutRequest to link those transactions to the customer.
+//
+// Parameters:
+//
+// request (CustomerRequest): The customer's details.
+//
+// Returns:
+//
+// *Customer: A pointer to the newly created customer.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	customer, err := client.CreateCustomer(paychangu.CustomerRequest{
+//	    Email:     "john@example.com",
+//	    FirstName: "John",
+//	    LastName:  "Doe",
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to create customer: %v", err)
+//	}
+//	fmt.Printf("Customer ID: %s\n", customer.ID)
+func (p *payChangu) CreateCustomer(request CustomerRequest) (*Customer, error) {
+	return p.CreateCustomerContext(context.Background(), request)
+}
+
+// CreateCustomerContext is like CreateCustomer but uses ctx for cancellation and deadlines.
+func (p *payChangu) CreateCustomerContext(ctx context.Context, request CustomerRequest) (*Customer, error) {
+	return p.saveCustomer(ctx, http.MethodPost, "/customers", request, Operation{Name: "CreateCustomer"})
+}
+
+// UpdateCustomer replaces the details of an existing customer.
+//
+// Parameters:
+//
+// customerID (string): The ID of the customer to update.
+//
+// request (CustomerRequest): The customer's new details.
+//
+// Returns:
+//
+// *Customer: A pointer to the updated customer.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	customer, err := client.UpdateCustomer("CUS_8a7d2f", paychangu.CustomerRequest{
+//	    Email:     "john.doe@example.com",
+//	    FirstName: "John",
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to update customer: %v", err)
+//	}
+func (p *payChangu) UpdateCustomer(customerID string, request CustomerRequest) (*Customer, error) {
+	return p.UpdateCustomerContext(context.Background(), customerID, request)
+}
+
+// UpdateCustomerContext is like UpdateCustomer but uses ctx for cancellation and deadlines.
+func (p *payChangu) UpdateCustomerContext(ctx context.Context, customerID string, request CustomerRequest) (*Customer, error) {
+	return p.saveCustomer(ctx, http.MethodPut, "/customers/"+url.PathEscape(customerID), request, Operation{Name: "UpdateCustomer"})
+}
+
+// saveCustomer sends a create or update request for a customer.
+func (p *payChangu) saveCustomer(ctx context.Context, method, path string, request CustomerRequest, op Operation) (*Customer, error) {
+	var response CustomerResponse
+	if err := p.call(ctx, op, method, path, request, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// ListCustomers retrieves the customers stored with PayChangu.
+//
+// Returns:
+//
+// []Customer: A slice of customers. Transactions are not populated.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	customers, err := client.ListCustomers()
+//	if err != nil {
+//	    log.Fatalf("Failed to list customers: %v", err)
+//	}
+//	for _, c := range customers {
+//	    fmt.Printf("Customer: %s %s (%s)\n", c.FirstName, c.LastName, c.ID)
+//	}
+func (p *payChangu) ListCustomers() ([]Customer, error) {
+	return p.ListCustomersContext(context.Background())
+}
+
+// ListCustomersContext is like ListCustomers but uses ctx for cancellation and deadlines.
+func (p *payChangu) ListCustomersContext(ctx context.Context) ([]Customer, error) {
+	var response CustomersResponse
+	op := Operation{Name: "ListCustomers"}
+	if err := p.call(ctx, op, http.MethodGet, "/customers", nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// GetCustomer retrieves a single customer together with the
+// payments that were linked to it through Request.CustomerID.
+//
+// Parameters:
+//
+// customerID (string): The ID of the customer to retrieve.
+//
+// Returns:
+//
+// *Customer: A pointer to the customer, with Transactions populated.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	customer, err := client.GetCustomer("CUS_8a7d2f")
+//	if err != nil {
+//	    log.Fatalf("Failed to get customer: %v", err)
+//	}
+//	for _, tx := range customer.Transactions {
+//	    fmt.Printf("%s: %.2f %s (%s)\n", tx.TxRef, tx.Amount, tx.Currency, tx.Status)
+//	}
+func (p *payChangu) GetCustomer(customerID string) (*Customer, error) {
+	return p.GetCustomerContext(context.Background(), customerID)
+}
+
+// GetCustomerContext is like GetCustomer but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetCustomerContext(ctx context.Context, customerID string) (*Customer, error) {
+	var response CustomerResponse
+	op := Operation{Name: "GetCustomer"}
+	if err := p.call(ctx, op, http.MethodGet, "/customers/"+url.PathEscape(customerID), nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return &response.Data, nil
+}
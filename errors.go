@@ -0,0 +1,115 @@
+package paychangu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// APIError is returned when the PayChangu API responds with a non-2xx
+// status. Use errors.As to inspect the status code or validation errors.
+//
+// Example Usage:
+//
+//	_, err := client.InitiateMobileMoneyPayout(payoutReq)
+//	var apiErr *paychangu.APIError
+//	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+//	    for field, messages := range apiErr.Fields {
+//	        fmt.Println(field, messages)
+//	    }
+//	}
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the error message from the API, if any.
+	Message string
+
+	// Fields holds per-field validation errors, if any.
+	Fields map[string][]string
+
+	// Body is the raw response body.
+	Body []byte
+}
+
+// newAPIError builds an APIError from an error response body. The API
+// reports "message" either as a string or as a map of validation errors.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: body}
+
+	var payload struct {
+		Message json.RawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Message) == 0 {
+		return apiErr
+	}
+
+	var message string
+	if err := json.Unmarshal(payload.Message, &message); err == nil {
+		apiErr.Message = message
+		return apiErr
+	}
+
+	var fields map[string][]string
+	if err := json.Unmarshal(payload.Message, &fields); err == nil {
+		apiErr.Fields = fields
+	}
+
+	return apiErr
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if len(e.Fields) > 0 {
+		names := make([]string, 0, len(e.Fields))
+		for name := range e.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var errorMessages []string
+		for _, name := range names {
+			for _, msg := range e.Fields[name] {
+				errorMessages = append(errorMessages, fmt.Sprintf("%s: %s", name, msg))
+			}
+		}
+		return fmt.Sprintf("API error (%d): validation failed: %s", e.StatusCode, strings.Join(errorMessages, "; "))
+	}
+
+	if e.Message != "" {
+		return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+	}
+
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, string(e.Body))
+}
+
+// IsTransient reports whether a call that failed with err may succeed
+// if retried: err is a network error, a 429 or 5xx status, an open
+// circuit breaker or an idempotency key still in progress. Other
+// errors, such as a 4xx status or a payout refused by the PayoutGuard,
+// fail again.
+//
+// A payout whose initiate call failed with a transient error may or may
+// not have been made. Before sending it again, look it up by ChargeID
+// with the payout details endpoint, and only send it if PayChangu has
+// no record of it (a 404).
+//
+// Example Usage:
+//
+//	_, err := client.InitiateMobileMoneyPayoutContext(ctx, payoutReq)
+//	if paychangu.IsTransient(err) {
+//	    details, lookupErr := client.GetMobileMoneyPayoutDetailsContext(ctx, payoutReq.ChargeID)
+//	    // A 404 means the payout was not made and can be sent again.
+//	}
+func IsTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrIdempotencyInProgress)
+}
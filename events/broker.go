@@ -0,0 +1,130 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Message is an event encoded for a message broker.
+type Message struct {
+	// Subject is the NATS subject or Kafka topic, e.g.
+	// "paychangu.payment.succeeded".
+	Subject string
+
+	// Key is the Reference of the event. Kafka adapters should use it as
+	// the message key, so the events of one payment stay in order.
+	Key string
+
+	// Data is the event as JSON.
+	Data []byte
+
+	// Headers carry the event ID and type, for brokers that support them.
+	Headers map[string]string
+}
+
+// Broker sends messages to a message broker. Adapters are a few lines
+// over the broker's client, e.g. for NATS:
+//
+//	type natsBroker struct{ nc *nats.Conn }
+//
+//	func (b natsBroker) Send(ctx context.Context, m events.Message) error {
+//	    return b.nc.Publish(m.Subject, m.Data)
+//	}
+//
+// and for Kafka with segmentio/kafka-go:
+//
+//	type kafkaBroker struct{ w *kafka.Writer }
+//
+//	func (b kafkaBroker) Send(ctx context.Context, m events.Message) error {
+//	    return b.w.WriteMessages(ctx, kafka.Message{Topic: m.Subject, Key: []byte(m.Key), Value: m.Data})
+//	}
+type Broker interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// BrokerPublisher is a Publisher that encodes events as JSON
+// and sends them to a Broker.
+type BrokerPublisher struct {
+	Broker Broker
+
+	// Prefix is prepended to the event type to make the subject.
+	// It defaults to "paychangu".
+	Prefix string
+}
+
+// Publish implements Publisher.
+func (p *BrokerPublisher) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = "paychangu"
+	}
+	return p.Broker.Send(ctx, Message{
+		Subject: prefix + "." + string(event.Type),
+		Key:     event.Reference,
+		Data:    data,
+		Headers: map[string]string{"event-id": event.ID, "event-type": string(event.Type)},
+	})
+}
+
+// Decode decodes the data of a message sent by a BrokerPublisher.
+func Decode(data []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Event{}, fmt.Errorf("failed to decode event: %w", err)
+	}
+	return event, nil
+}
+
+// FakeBroker is an in-memory Broker for tests. It keeps the messages
+// sent to it and delivers them to its subscribers.
+type FakeBroker struct {
+	// Err, if set, is returned by Send instead of accepting the message.
+	Err error
+
+	mu          sync.Mutex
+	messages    []Message
+	subscribers map[string][]func(Message)
+}
+
+// Send implements Broker.
+func (b *FakeBroker) Send(ctx context.Context, message Message) error {
+	b.mu.Lock()
+	if b.Err != nil {
+		b.mu.Unlock()
+		return b.Err
+	}
+	b.messages = append(b.messages, message)
+	subscribers := append([]func(Message){}, b.subscribers[message.Subject]...)
+	subscribers = append(subscribers, b.subscribers[""]...)
+	b.mu.Unlock()
+
+	for _, deliver := range subscribers {
+		deliver(message)
+	}
+	return nil
+}
+
+// Subscribe calls deliver with every message sent to subject,
+// or to any subject if subject is empty.
+func (b *FakeBroker) Subscribe(subject string, deliver func(Message)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = make(map[string][]func(Message))
+	}
+	b.subscribers[subject] = append(b.subscribers[subject], deliver)
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (b *FakeBroker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing to a closed Channel.
+var ErrClosed = errors.New("events: publisher is closed")
+
+// Channel is a Publisher that delivers events on a Go channel, for a
+// consumer running in its own goroutine.
+//
+// Example Usage:
+//
+//	ch := events.NewChannel(100)
+//	go func() {
+//	    for e := range ch.Events() {
+//	        notify(e)
+//	    }
+//	}()
+//	emitter := &events.Emitter{Publisher: ch}
+type Channel struct {
+	ch   chan Event
+	done chan struct{}
+
+	mu         sync.RWMutex
+	closed     bool
+	publishing sync.WaitGroup
+}
+
+// NewChannel creates a Channel buffering up to size events.
+func NewChannel(size int) *Channel {
+	return &Channel{ch: make(chan Event, size), done: make(chan struct{})}
+}
+
+// Events returns the channel events are delivered on. It is closed
+// after Close, once no publish is in progress.
+func (c *Channel) Events() <-chan Event {
+	return c.ch
+}
+
+// Publish implements Publisher. It waits for room in the buffer until
+// ctx is done or the Channel is closed.
+func (c *Channel) Publish(ctx context.Context, event Event) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return ErrClosed
+	}
+	c.publishing.Add(1)
+	c.mu.RUnlock()
+	defer c.publishing.Done()
+
+	select {
+	case c.ch <- event:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the Channel without waiting for its consumer. Publishes
+// blocked on a full buffer, and later ones, fail with ErrClosed. The
+// channel returned by Events is closed once the blocked publishes have
+// returned; the events already buffered can still be received.
+func (c *Channel) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+	go func() {
+		c.publishing.Wait()
+		close(c.ch)
+	}()
+}
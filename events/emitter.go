@@ -0,0 +1,299 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/outbox"
+	"github.com/santinalbrowns/paychangu/payout"
+	"github.com/santinalbrowns/paychangu/schedule"
+)
+
+// Emitter publishes the events observed by the SDK's helpers. Its
+// methods plug into the client, webhooks, payout.Machine, outbox.Relay
+// and schedule.Scheduler.
+//
+// Where a helper can report a failure to publish, as webhooks and the
+// outbox can, the error is returned so that the event is delivered
+// again. Elsewhere it is passed to OnError; use the outbox when every
+// event must be delivered.
+type Emitter struct {
+	// Publisher receives the events. It is required.
+	Publisher Publisher
+
+	// OnError, if set, receives the events that could not be published
+	// by helpers that cannot return the error.
+	OnError func(ctx context.Context, event Event, err error)
+}
+
+// Emit publishes event, setting its ID and Time if they are unset.
+func (e *Emitter) Emit(ctx context.Context, event Event) error {
+	if event.ID == "" {
+		event.ID = paychangu.NewReference("EVT")
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	return e.Publisher.Publish(ctx, event)
+}
+
+// emit publishes event, passing errors to OnError.
+func (e *Emitter) emit(ctx context.Context, event Event) {
+	if err := e.Emit(ctx, event); err != nil && e.OnError != nil {
+		e.OnError(ctx, event, err)
+	}
+}
+
+// Client is the part of the PayChangu client whose calls an Emitter observes.
+type Client interface {
+	InitiatePaymentContext(ctx context.Context, request paychangu.Request) (*paychangu.Response, error)
+	VerifyPaymentContext(ctx context.Context, txRef string) (*paychangu.VerifyPaymentResponse, error)
+	InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error)
+	InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error)
+	GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error)
+	GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error)
+}
+
+// Client returns a Client that calls c and publishes:
+//
+//   - payment.initiated when InitiatePayment succeeds;
+//   - payment.succeeded or payment.failed when VerifyPayment
+//     reports a final status;
+//   - payout.initiated when a payout is accepted, and payout.failed
+//     when it is rejected;
+//   - payout.succeeded, payout.failed or payout.reversed when the
+//     payout details report a final status.
+func (e *Emitter) Client(c Client) Client {
+	return &client{Client: c, emitter: e}
+}
+
+// client wraps a Client to publish events.
+type client struct {
+	Client
+	emitter *Emitter
+}
+
+// InitiatePaymentContext implements Client.
+func (c *client) InitiatePaymentContext(ctx context.Context, request paychangu.Request) (*paychangu.Response, error) {
+	resp, err := c.Client.InitiatePaymentContext(ctx, request)
+	if err == nil {
+		c.emitter.emit(ctx, Event{
+			Type:      PaymentInitiated,
+			Reference: request.TxRef,
+			Amount:    float64(request.Amount),
+			Currency:  request.Currency,
+			Source:    SourceAPI,
+		})
+	}
+	return resp, err
+}
+
+// VerifyPaymentContext implements Client.
+func (c *client) VerifyPaymentContext(ctx context.Context, txRef string) (*paychangu.VerifyPaymentResponse, error) {
+	resp, err := c.Client.VerifyPaymentContext(ctx, txRef)
+	if err != nil {
+		return resp, err
+	}
+	if typ, ok := paymentType(resp.Data.Status); ok {
+		c.emitter.emit(ctx, Event{
+			Type:      typ,
+			Reference: txRef,
+			Amount:    resp.Data.Amount,
+			Currency:  resp.Data.Currency,
+			Status:    resp.Data.Status,
+			Source:    SourceVerify,
+		})
+	}
+	return resp, err
+}
+
+// InitiateMobileMoneyPayoutContext implements Client.
+func (c *client) InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error) {
+	resp, err := c.Client.InitiateMobileMoneyPayoutContext(ctx, request)
+	var status string
+	if err == nil {
+		status = resp.Data.Transaction.Status
+	}
+	c.payoutInitiated(ctx, request.ChargeID, request.Amount, status, err)
+	return resp, err
+}
+
+// InitiateBankPayoutContext implements Client.
+func (c *client) InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error) {
+	resp, err := c.Client.InitiateBankPayoutContext(ctx, request)
+	var status string
+	if err == nil {
+		status = resp.Data.Transaction.Status
+	}
+	c.payoutInitiated(ctx, request.ChargeID, request.Amount, status, err)
+	return resp, err
+}
+
+// payoutInitiated publishes the outcome of an initiate payout call. Errors
+// other than a rejection leave the outcome unknown and publish nothing.
+func (c *client) payoutInitiated(ctx context.Context, chargeID string, amount float64, status string, err error) {
+	event := Event{Type: PayoutInitiated, Reference: chargeID, Amount: amount, Status: status, Source: SourceAPI}
+	if err != nil {
+		if !rejected(err) {
+			return
+		}
+		event.Type = PayoutFailed
+		event.Error = err.Error()
+	}
+	c.emitter.emit(ctx, event)
+}
+
+// GetMobileMoneyPayoutDetailsContext implements Client.
+func (c *client) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error) {
+	details, err := c.Client.GetMobileMoneyPayoutDetailsContext(ctx, chargeID)
+	if err == nil {
+		c.payoutPolled(ctx, chargeID, details.Status)
+	}
+	return details, err
+}
+
+// GetBankPayoutDetailsContext implements Client.
+func (c *client) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error) {
+	details, err := c.Client.GetBankPayoutDetailsContext(ctx, chargeID)
+	if err == nil {
+		c.payoutPolled(ctx, chargeID, details.Status)
+	}
+	return details, err
+}
+
+// payoutPolled publishes the status reported by the payout details, if final.
+func (c *client) payoutPolled(ctx context.Context, chargeID, status string) {
+	if typ, ok := payoutType(status); ok {
+		c.emitter.emit(ctx, Event{Type: typ, Reference: chargeID, Status: status, Source: SourcePoll})
+	}
+}
+
+// rejected reports whether err is PayChangu refusing a request, as
+// opposed to a failure that leaves its outcome unknown.
+func rejected(err error) bool {
+	var apiErr *paychangu.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests
+}
+
+// WebhookHandler returns a paychangu.WebhookHandler that calls next, if
+// set, and then publishes the event if its status is final. If next
+// fails nothing is published; if publishing fails the error is returned,
+// so that PayChangu delivers the webhook again.
+func (e *Emitter) WebhookHandler(next paychangu.WebhookHandler) paychangu.WebhookHandler {
+	return func(ctx context.Context, webhook *paychangu.WebhookEvent) error {
+		if next != nil {
+			if err := next(ctx, webhook); err != nil {
+				return err
+			}
+		}
+
+		typ, ok := paymentType(webhook.Status)
+		if webhook.ChargeID != "" || strings.Contains(webhook.EventType, "payout") {
+			typ, ok = payoutType(webhook.Status)
+		}
+		if !ok {
+			return nil
+		}
+		amount, _ := webhook.Amount.Float64()
+		return e.Emit(ctx, Event{
+			Type:      typ,
+			Reference: webhook.Ref(),
+			Amount:    amount,
+			Currency:  webhook.Currency,
+			Status:    webhook.Status,
+			Source:    SourceWebhook,
+		})
+	}
+}
+
+// PayoutTransition publishes the transitions of a payout.Machine to
+// pending, successful, failed and reversed. Set it as the machine's
+// OnTransition.
+func (e *Emitter) PayoutTransition(ctx context.Context, p *payout.Payout, t payout.Transition) {
+	var typ Type
+	switch t.To {
+	case payout.StatePending:
+		typ = PayoutInitiated
+	case payout.StateSuccessful:
+		typ = PayoutSucceeded
+	case payout.StateFailed:
+		typ = PayoutFailed
+	case payout.StateReversed:
+		typ = PayoutReversed
+	default:
+		return
+	}
+
+	event := Event{Type: typ, Reference: p.ID, Amount: p.Amount(), Source: SourceWorkflow, Time: t.At}
+	if typ == PayoutFailed {
+		event.Error = t.Reason
+	}
+	e.emit(ctx, event)
+}
+
+// OutboxSink returns an outbox.Sink that publishes the outcomes of
+// outbox messages: payment.initiated or payout.initiated when PayChangu
+// accepted them, and payment.failed or payout.failed otherwise. A payout
+// whose result reports a final status, as when a retry found it with
+// the payout details endpoint, is published as payout.succeeded,
+// payout.failed or payout.reversed.
+func (e *Emitter) OutboxSink() outbox.Sink {
+	return outbox.SinkFunc(func(ctx context.Context, m outbox.Event) error {
+		event := Event{Reference: m.MessageID, Error: m.Error, Source: SourceOutbox, Time: m.At}
+		switch {
+		case m.Operation == outbox.OpPayment && m.Status == outbox.StatusSucceeded:
+			event.Type = PaymentInitiated
+		case m.Operation == outbox.OpPayment:
+			event.Type = PaymentFailed
+		case m.Status == outbox.StatusSucceeded:
+			event.Type = PayoutInitiated
+		default:
+			event.Type = PayoutFailed
+		}
+
+		// The result is an initiate response, with the status under
+		// data.transaction, or payout details, with it at the top.
+		var result struct {
+			Status string `json:"status"`
+			Data   struct {
+				Transaction struct {
+					Status string `json:"status"`
+				} `json:"transaction"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(m.Result, &result) == nil {
+			event.Status = result.Data.Transaction.Status
+			if event.Status == "" {
+				event.Status = result.Status
+			}
+		}
+		if m.Operation != outbox.OpPayment {
+			if t, ok := payoutType(event.Status); ok {
+				event.Type = t
+			}
+		}
+		return e.Emit(ctx, event)
+	})
+}
+
+// ScheduleRun publishes the runs of a schedule.Scheduler: payout.initiated
+// for succeeded runs and payout.failed for failed ones. Set it as the
+// scheduler's OnRun.
+func (e *Emitter) ScheduleRun(ctx context.Context, run *schedule.Run) {
+	event := Event{Reference: run.ID, Amount: run.Amount, Status: run.PayoutStatus, Source: SourceSchedule, Time: run.FinishedAt}
+	switch run.Status {
+	case schedule.RunSucceeded:
+		event.Type = PayoutInitiated
+	case schedule.RunFailed:
+		event.Type = PayoutFailed
+		event.Error = run.Error
+	default:
+		return
+	}
+	e.emit(ctx, event)
+}
@@ -0,0 +1,318 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/outbox"
+	"github.com/santinalbrowns/paychangu/payout"
+	"github.com/santinalbrowns/paychangu/schedule"
+)
+
+// fakeClient is a Client answering with fixed statuses, or err.
+type fakeClient struct {
+	status string
+	err    error
+}
+
+func (c *fakeClient) InitiatePaymentContext(ctx context.Context, request paychangu.Request) (*paychangu.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &paychangu.Response{Status: "success"}, nil
+}
+
+func (c *fakeClient) VerifyPaymentContext(ctx context.Context, txRef string) (*paychangu.VerifyPaymentResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := &paychangu.VerifyPaymentResponse{Status: "success"}
+	resp.Data.Status = c.status
+	resp.Data.Amount = 5000
+	resp.Data.Currency = "MWK"
+	return resp, nil
+}
+
+func (c *fakeClient) InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := &paychangu.MobileMoneyPayoutResponse{Status: "success"}
+	resp.Data.Transaction.Status = c.status
+	return resp, nil
+}
+
+func (c *fakeClient) InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := &paychangu.BankPayoutResponse{Status: "success"}
+	resp.Data.Transaction.Status = c.status
+	return resp, nil
+}
+
+func (c *fakeClient) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &paychangu.PayoutTransactionDetails{ChargeID: chargeID, Status: c.status}, nil
+}
+
+func (c *fakeClient) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &paychangu.BankPayoutTransactionDetails{ChargeID: chargeID, Status: c.status}, nil
+}
+
+// published returns the types of the events published to m.
+func published(m *Memory) []Type {
+	var types []Type
+	for _, e := range m.Events() {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func equalTypes(got, want []Type) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEmit(t *testing.T) {
+	bus := NewMemory()
+	emitter := &Emitter{Publisher: bus}
+	if err := emitter.Emit(context.Background(), Event{Type: PaymentInitiated, Reference: "TX-1"}); err != nil {
+		t.Fatal(err)
+	}
+	e := bus.Events()[0]
+	if e.ID == "" || e.Time.IsZero() {
+		t.Errorf("event = %+v, want an ID and a time", e)
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		client *fakeClient
+		call   func(c Client) error
+		want   []Type
+	}{
+		{
+			name:   "payment initiated",
+			client: &fakeClient{},
+			call: func(c Client) error {
+				_, err := c.InitiatePaymentContext(ctx, paychangu.Request{TxRef: "TX-1"})
+				return err
+			},
+			want: []Type{PaymentInitiated},
+		},
+		{
+			name:   "payment paid",
+			client: &fakeClient{status: "success"},
+			call: func(c Client) error {
+				_, err := c.VerifyPaymentContext(ctx, "TX-1")
+				return err
+			},
+			want: []Type{PaymentSucceeded},
+		},
+		{
+			name:   "payment still pending",
+			client: &fakeClient{status: "pending"},
+			call: func(c Client) error {
+				_, err := c.VerifyPaymentContext(ctx, "TX-1")
+				return err
+			},
+		},
+		{
+			name:   "payout accepted",
+			client: &fakeClient{status: "pending"},
+			call: func(c Client) error {
+				_, err := c.InitiateMobileMoneyPayoutContext(ctx, paychangu.MobileMoneyPayoutRequest{ChargeID: "PAYOUT-1"})
+				return err
+			},
+			want: []Type{PayoutInitiated},
+		},
+		{
+			name:   "payout rejected",
+			client: &fakeClient{err: &paychangu.APIError{StatusCode: http.StatusBadRequest}},
+			call: func(c Client) error {
+				_, err := c.InitiateBankPayoutContext(ctx, paychangu.BankPayoutRequest{ChargeID: "PAYOUT-1"})
+				return err
+			},
+			want: []Type{PayoutFailed},
+		},
+		{
+			name:   "payout outcome unknown",
+			client: &fakeClient{err: &paychangu.APIError{StatusCode: http.StatusBadGateway}},
+			call: func(c Client) error {
+				_, err := c.InitiateMobileMoneyPayoutContext(ctx, paychangu.MobileMoneyPayoutRequest{ChargeID: "PAYOUT-1"})
+				return err
+			},
+		},
+		{
+			name:   "payout reversed",
+			client: &fakeClient{status: "reversed"},
+			call: func(c Client) error {
+				_, err := c.GetBankPayoutDetailsContext(ctx, "PAYOUT-1")
+				return err
+			},
+			want: []Type{PayoutReversed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewMemory()
+			emitter := &Emitter{Publisher: bus}
+			err := tt.call(emitter.Client(tt.client))
+			if !errors.Is(err, tt.client.err) {
+				t.Fatalf("err = %v, want %v", err, tt.client.err)
+			}
+			if got := published(bus); !equalTypes(got, tt.want) {
+				t.Errorf("published %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientOnError(t *testing.T) {
+	failed := errors.New("broker down")
+	var got error
+	emitter := &Emitter{
+		Publisher: PublisherFunc(func(ctx context.Context, e Event) error { return failed }),
+		OnError:   func(ctx context.Context, e Event, err error) { got = err },
+	}
+	_, err := emitter.Client(&fakeClient{}).InitiatePaymentContext(context.Background(), paychangu.Request{TxRef: "TX-1"})
+	if err != nil {
+		t.Fatalf("the call failed with the publisher: %v", err)
+	}
+	if got != failed {
+		t.Errorf("OnError got %v, want %v", got, failed)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	ctx := context.Background()
+	bus := NewMemory()
+	emitter := &Emitter{Publisher: bus}
+	handle := emitter.WebhookHandler(nil)
+
+	webhooks := []*paychangu.WebhookEvent{
+		{EventType: "checkout.payment", Status: "success", TxRef: "TX-1", Amount: "5000", Currency: "MWK"},
+		{EventType: "payout", Status: "failed", ChargeID: "PAYOUT-1"},
+		{EventType: "checkout.payment", Status: "pending", TxRef: "TX-2"},
+	}
+	for _, webhook := range webhooks {
+		if err := handle(ctx, webhook); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := published(bus), []Type{PaymentSucceeded, PayoutFailed}; !equalTypes(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	if e := bus.Events()[0]; e.Reference != "TX-1" || e.Amount != 5000 || e.Source != SourceWebhook {
+		t.Errorf("event = %+v", e)
+	}
+
+	// A failing handler publishes nothing, and a failing publisher
+	// fails the webhook so that it is delivered again.
+	failed := errors.New("failed")
+	bus = NewMemory()
+	emitter.Publisher = bus
+	handle = emitter.WebhookHandler(func(ctx context.Context, webhook *paychangu.WebhookEvent) error { return failed })
+	if err := handle(ctx, webhooks[0]); err != failed || len(bus.Events()) != 0 {
+		t.Errorf("err = %v with %d events, want the handler error and none", err, len(bus.Events()))
+	}
+	emitter.Publisher = PublisherFunc(func(ctx context.Context, e Event) error { return failed })
+	if err := emitter.WebhookHandler(nil)(ctx, webhooks[0]); err != failed {
+		t.Errorf("err = %v, want the publisher error", err)
+	}
+}
+
+func TestPayoutTransition(t *testing.T) {
+	bus := NewMemory()
+	emitter := &Emitter{Publisher: bus}
+	p := &payout.Payout{ID: "PAYOUT-1", MobileMoney: &paychangu.MobileMoneyPayoutRequest{Amount: 1000}}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tr := range []payout.Transition{
+		{From: payout.StateDraft, To: payout.StateApproved, At: at},
+		{From: payout.StateSubmitted, To: payout.StatePending, At: at},
+		{From: payout.StatePending, To: payout.StateFailed, At: at, Reason: "insufficient balance"},
+	} {
+		emitter.PayoutTransition(context.Background(), p, tr)
+	}
+
+	if got, want := published(bus), []Type{PayoutInitiated, PayoutFailed}; !equalTypes(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	e := bus.Events()[1]
+	if e.Reference != "PAYOUT-1" || e.Amount != 1000 || e.Error != "insufficient balance" || !e.Time.Equal(at) {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestOutboxSink(t *testing.T) {
+	bus := NewMemory()
+	sink := (&Emitter{Publisher: bus}).OutboxSink()
+
+	result, _ := json.Marshal(map[string]any{"status": "success", "data": map[string]any{"transaction": map[string]any{"status": "pending"}}})
+	details := func(status string) json.RawMessage {
+		data, _ := json.Marshal(paychangu.PayoutTransactionDetails{ChargeID: "PAYOUT-3", Status: status})
+		return data
+	}
+	for _, m := range []outbox.Event{
+		{MessageID: "TX-1", Operation: outbox.OpPayment, Status: outbox.StatusSucceeded},
+		{MessageID: "TX-2", Operation: outbox.OpPayment, Status: outbox.StatusFailed, Error: "bad request"},
+		{MessageID: "PAYOUT-1", Operation: outbox.OpMobileMoneyPayout, Status: outbox.StatusSucceeded, Result: result},
+		{MessageID: "PAYOUT-2", Operation: outbox.OpBankPayout, Status: outbox.StatusFailed},
+		{MessageID: "PAYOUT-3", Operation: outbox.OpMobileMoneyPayout, Status: outbox.StatusSucceeded, Result: details("successful")},
+		{MessageID: "PAYOUT-4", Operation: outbox.OpMobileMoneyPayout, Status: outbox.StatusFailed, Result: details("failed")},
+		{MessageID: "PAYOUT-5", Operation: outbox.OpBankPayout, Status: outbox.StatusFailed, Result: details("reversed")},
+	} {
+		if err := sink.Publish(context.Background(), m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Type{PaymentInitiated, PaymentFailed, PayoutInitiated, PayoutFailed, PayoutSucceeded, PayoutFailed, PayoutReversed}
+	if got := published(bus); !equalTypes(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	events := bus.Events()
+	if events[1].Error != "bad request" || events[2].Status != "pending" || events[6].Status != "reversed" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestScheduleRun(t *testing.T) {
+	bus := NewMemory()
+	emitter := &Emitter{Publisher: bus}
+	for _, run := range []*schedule.Run{
+		{ID: "RUN-1", Amount: 1000, Status: schedule.RunSucceeded, PayoutStatus: "pending"},
+		{ID: "RUN-2", Status: schedule.RunFailed, Error: "rejected"},
+		{ID: "RUN-3", Status: schedule.RunSkipped},
+	} {
+		emitter.ScheduleRun(context.Background(), run)
+	}
+
+	if got, want := published(bus), []Type{PayoutInitiated, PayoutFailed}; !equalTypes(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	if e := bus.Events()[1]; e.Reference != "RUN-2" || e.Error != "rejected" || e.Source != SourceSchedule {
+		t.Errorf("event = %+v", e)
+	}
+}
@@ -0,0 +1,137 @@
+// Package events publishes the lifecycle of payments and payouts, so
+// that other services learn when a payment succeeds or a payout fails
+// without polling PayChangu themselves.
+//
+// An Emitter turns what the SDK observes, from API calls, webhooks, the
+// payout workflow, the outbox relay and the payout scheduler, into typed
+// Events and hands them to a Publisher. Publishers are provided for
+// in-process use (Memory and Channel) and for message brokers such as
+// NATS or Kafka (BrokerPublisher).
+//
+// Example Usage:
+//
+//	bus := events.NewMemory()
+//	bus.Subscribe(func(ctx context.Context, e events.Event) error {
+//	    log.Printf("%s %s", e.Type, e.Reference)
+//	    return nil
+//	}, events.PaymentSucceeded, events.PaymentFailed)
+//
+//	emitter := &events.Emitter{Publisher: bus}
+//	client := emitter.Client(paychangu.New("your_secret_key"))
+//	resp, err := client.VerifyPaymentContext(ctx, txRef) // publishes payment.succeeded
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Type is the type of an event.
+type Type string
+
+// Event types.
+const (
+	// PaymentInitiated is published when a checkout is created.
+	PaymentInitiated Type = "payment.initiated"
+
+	// PaymentSucceeded is published when a payment is reported paid.
+	PaymentSucceeded Type = "payment.succeeded"
+
+	// PaymentFailed is published when a payment is reported failed,
+	// or could not be initiated.
+	PaymentFailed Type = "payment.failed"
+
+	// PayoutInitiated is published when PayChangu accepts a payout.
+	PayoutInitiated Type = "payout.initiated"
+
+	// PayoutSucceeded is published when a payout is reported paid.
+	PayoutSucceeded Type = "payout.succeeded"
+
+	// PayoutFailed is published when a payout is reported failed,
+	// or was rejected.
+	PayoutFailed Type = "payout.failed"
+
+	// PayoutReversed is published when a successful payout is reversed.
+	PayoutReversed Type = "payout.reversed"
+)
+
+// Source is where the SDK learned of an event.
+type Source string
+
+// Event sources.
+const (
+	SourceAPI      Source = "api"
+	SourceVerify   Source = "verify"
+	SourcePoll     Source = "poll"
+	SourceWebhook  Source = "webhook"
+	SourceWorkflow Source = "workflow"
+	SourceOutbox   Source = "outbox"
+	SourceSchedule Source = "schedule"
+)
+
+// Event is a step in the lifecycle of a payment or payout. The same
+// step may be observed from several sources, e.g. a webhook and a
+// verification, so consumers should deduplicate by Reference and Type.
+type Event struct {
+	// ID identifies the event.
+	ID   string `json:"id"`
+	Type Type   `json:"type"`
+
+	// Reference is the TxRef of a payment or the ChargeID of a payout.
+	Reference string `json:"reference"`
+
+	Amount   float64 `json:"amount,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+
+	// Status is the status reported by PayChangu, if any.
+	Status string `json:"status,omitempty"`
+
+	// Error describes why a payment or payout failed, if known.
+	Error string `json:"error,omitempty"`
+
+	Source Source    `json:"source"`
+	Time   time.Time `json:"time"`
+}
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, event Event) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// paymentType returns the type of a payment event with status,
+// if the status is final.
+func paymentType(status string) (Type, bool) {
+	switch paychangu.NormalizeStatus(status) {
+	case paychangu.StatusSuccess:
+		return PaymentSucceeded, true
+	case paychangu.StatusFailed, paychangu.StatusReversed:
+		return PaymentFailed, true
+	default:
+		return "", false
+	}
+}
+
+// payoutType returns the type of a payout event with status,
+// if the status is final.
+func payoutType(status string) (Type, bool) {
+	switch paychangu.NormalizeStatus(status) {
+	case paychangu.StatusSuccess:
+		return PayoutSucceeded, true
+	case paychangu.StatusFailed:
+		return PayoutFailed, true
+	case paychangu.StatusReversed:
+		return PayoutReversed, true
+	default:
+		return "", false
+	}
+}
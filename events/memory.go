@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Handler handles a published event.
+type Handler func(ctx context.Context, event Event) error
+
+// Memory is an in-process Publisher. It calls the handlers subscribed to
+// an event synchronously, in the order they subscribed, and keeps every
+// event published, which makes it suitable for tests too.
+type Memory struct {
+	mu          sync.Mutex
+	subscribers []*subscriber
+	events      []Event
+}
+
+// subscriber is a handler and the event types it is subscribed to.
+type subscriber struct {
+	handle Handler
+	types  map[Type]bool
+}
+
+// NewMemory creates a Memory publisher without subscribers.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Subscribe calls handle for every event of the given types, or of any
+// type if none are given. It returns a function that unsubscribes.
+func (m *Memory) Subscribe(handle Handler, types ...Type) (unsubscribe func()) {
+	s := &subscriber{handle: handle}
+	if len(types) > 0 {
+		s.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	m.mu.Lock()
+	m.subscribers = append(m.subscribers, s)
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, other := range m.subscribers {
+			if other == s {
+				m.subscribers = append(m.subscribers[:i:i], m.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish implements Publisher. Every matching handler is called, and
+// their errors are returned joined.
+func (m *Memory) Publish(ctx context.Context, event Event) error {
+	m.mu.Lock()
+	m.events = append(m.events, event)
+	subscribers := append([]*subscriber(nil), m.subscribers...)
+	m.mu.Unlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if s.types != nil && !s.types[event.Type] {
+			continue
+		}
+		if err := s.handle(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Events returns the events published so far, oldest first.
+func (m *Memory) Events() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Event(nil), m.events...)
+}
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBrokerRoundTrip(t *testing.T) {
+	broker := &FakeBroker{}
+	var received []Event
+	broker.Subscribe("paychangu.payment.succeeded", func(m Message) {
+		e, err := Decode(m.Data)
+		if err != nil {
+			t.Error(err)
+		}
+		received = append(received, e)
+	})
+
+	emitter := &Emitter{Publisher: &BrokerPublisher{Broker: broker}}
+	sent := Event{Type: PaymentSucceeded, Reference: "TX-1", Amount: 5000, Currency: "MWK", Status: "success", Source: SourceVerify}
+	if err := emitter.Emit(context.Background(), sent); err != nil {
+		t.Fatal(err)
+	}
+	if err := emitter.Emit(context.Background(), Event{Type: PayoutFailed, Reference: "PAYOUT-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := broker.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(messages))
+	}
+	m := messages[0]
+	if m.Subject != "paychangu.payment.succeeded" || m.Key != "TX-1" || m.Headers["event-type"] != string(PaymentSucceeded) {
+		t.Errorf("message = %+v", m)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("received %d events, want 1", len(received))
+	}
+	got := received[0]
+	if got.ID == "" || got.ID != m.Headers["event-id"] || got.Time.IsZero() {
+		t.Errorf("decoded %+v, want the ID of the headers and a time", got)
+	}
+	got.ID, got.Time = "", time.Time{}
+	if got != sent {
+		t.Errorf("decoded %+v, want %+v", got, sent)
+	}
+
+	if _, err := Decode([]byte("not json")); err == nil {
+		t.Error("Decode: want an error for invalid data")
+	}
+
+	broker.Err = errors.New("unavailable")
+	if err := emitter.Emit(context.Background(), sent); err != broker.Err {
+		t.Errorf("err = %v, want the broker error", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	bus := NewMemory()
+
+	var all, payments int
+	bus.Subscribe(func(ctx context.Context, e Event) error {
+		all++
+		return nil
+	})
+	failed := errors.New("failed")
+	unsubscribe := bus.Subscribe(func(ctx context.Context, e Event) error {
+		payments++
+		return failed
+	}, PaymentSucceeded, PaymentFailed)
+
+	if err := bus.Publish(ctx, Event{Type: PaymentSucceeded}); !errors.Is(err, failed) {
+		t.Errorf("err = %v, want the handler error", err)
+	}
+	if err := bus.Publish(ctx, Event{Type: PayoutSucceeded}); err != nil {
+		t.Error(err)
+	}
+	unsubscribe()
+	if err := bus.Publish(ctx, Event{Type: PaymentFailed}); err != nil {
+		t.Error(err)
+	}
+
+	if all != 3 || payments != 1 {
+		t.Errorf("handled %d and %d events, want 3 and 1", all, payments)
+	}
+	if len(bus.Events()) != 3 {
+		t.Errorf("kept %d events, want 3", len(bus.Events()))
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ctx := context.Background()
+	ch := NewChannel(1)
+
+	if err := ch.Publish(ctx, Event{Reference: "TX-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The buffer is full, so this publish waits for the consumer.
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := ch.Publish(timeout, Event{Reference: "TX-2"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context error", err)
+	}
+
+	ch.Close()
+	ch.Close()
+	if err := ch.Publish(ctx, Event{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("err = %v, want ErrClosed", err)
+	}
+
+	var got []string
+	for e := range ch.Events() {
+		got = append(got, e.Reference)
+	}
+	if len(got) != 1 || got[0] != "TX-1" {
+		t.Errorf("received %v, want [TX-1]", got)
+	}
+}
+
+func TestChannelCloseWithBlockedPublisher(t *testing.T) {
+	ctx := context.Background()
+	ch := NewChannel(1)
+	if err := ch.Publish(ctx, Event{Reference: "TX-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	published := make(chan error)
+	go func() {
+		published <- ch.Publish(ctx, Event{Reference: "TX-2"})
+	}()
+	// Give the publish time to block on the full buffer.
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		ch.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for the blocked publish")
+	}
+
+	select {
+	case err := <-published:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("err = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the blocked publish did not return")
+	}
+
+	var got []string
+	for e := range ch.Events() {
+		got = append(got, e.Reference)
+	}
+	if len(got) != 1 || got[0] != "TX-1" {
+		t.Errorf("received %v, want [TX-1]", got)
+	}
+}
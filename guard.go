@@ -0,0 +1,58 @@
+package paychangu
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPayoutRefused is wrapped by the errors of a PayoutGuard that refuses
+// a payout for good, as opposed to failing to check it. Workflows such as
+// payout.Machine fail a refused payout instead of sending it again later.
+var ErrPayoutRefused = errors.New("paychangu: payout refused")
+
+// PayoutCheck describes a payout about to be sent, for a PayoutGuard.
+type PayoutCheck struct {
+	// Operation is "InitiateMobileMoneyPayout" or "InitiateBankPayout".
+	Operation string
+
+	ChargeID string
+	Amount   float64
+
+	// Currency is the currency of Amount. Payout requests carry none,
+	// since PayChangu pays out in Malawi kwacha, so it is "MWK".
+	Currency string
+
+	// Recipient is the mobile number or bank account number paid.
+	Recipient string
+
+	// Provider is the mobile money operator ref ID or the bank UUID.
+	Provider string
+}
+
+// PayoutGuard vets payouts before they are sent. Returning an error
+// stops the payout, and the error is returned to the caller as is.
+// Errors refusing the payout should wrap ErrPayoutRefused; other errors
+// are taken to mean it could not be checked, and may be retried.
+type PayoutGuard interface {
+	CheckPayout(ctx context.Context, payout PayoutCheck) error
+}
+
+// WithPayoutGuard makes InitiateMobileMoneyPayout and InitiateBankPayout
+// consult guard before every payout sent to the API. Payouts answered
+// from an IdempotencyStore are not checked again.
+func WithPayoutGuard(guard PayoutGuard) Option {
+	return func(p *payChangu) {
+		p.guard = guard
+	}
+}
+
+// payoutCurrency is the currency of every payout.
+const payoutCurrency = "MWK"
+
+// checkPayout consults the guard, if any.
+func (p *payChangu) checkPayout(ctx context.Context, payout PayoutCheck) error {
+	if p.guard == nil {
+		return nil
+	}
+	return p.guard.CheckPayout(ctx, payout)
+}
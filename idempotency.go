@@ -0,0 +1,293 @@
+package paychangu
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrIdempotencyKeyReused is returned when a ChargeID or TxRef that was
+// already used is sent again with a different request payload.
+var ErrIdempotencyKeyReused = errors.New("paychangu: idempotency key reused with a different request")
+
+// ErrIdempotencyInProgress is returned when a ChargeID or TxRef is sent
+// again while an earlier call for it may still be in flight. Try again
+// later: once the earlier call is older than the stale window, it is
+// reconciled. IsTransient reports it as transient.
+var ErrIdempotencyInProgress = errors.New("paychangu: idempotency key is in progress")
+
+// defaultIdempotencyStaleAfter is how long a pending key is
+// assumed to be in flight unless WithIdempotencyStaleAfter is used.
+const defaultIdempotencyStaleAfter = 5 * time.Minute
+
+// Idempotency record statuses.
+const (
+	// IdempotencyPending means the call was started but its outcome is
+	// unknown, e.g. because it timed out.
+	IdempotencyPending = "pending"
+
+	// IdempotencyCompleted means the call succeeded and its result is stored.
+	IdempotencyCompleted = "completed"
+)
+
+// IdempotencyRecord is what an IdempotencyStore keeps per key.
+type IdempotencyRecord struct {
+	// Key identifies the business event, e.g. "mobile_money_payout:<charge_id>",
+	// prefixed with "<merchant_id>/" for the clients of a Registry.
+	Key string
+
+	// Fingerprint is a hash of the request payload.
+	Fingerprint string
+
+	// Status is IdempotencyPending or IdempotencyCompleted.
+	Status string
+
+	// Result is the JSON encoded response of a completed call.
+	Result []byte
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IdempotencyStore records the payments and payouts sent to PayChangu so
+// that a repeated call for the same ChargeID or TxRef does not pay twice.
+// Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Begin records key as pending with the given fingerprint. If a
+	// record for key already exists, it is returned with created false.
+	Begin(ctx context.Context, key, fingerprint string) (record *IdempotencyRecord, created bool, err error)
+
+	// Complete marks key as completed with the given result.
+	Complete(ctx context.Context, key string, result []byte) error
+
+	// Release deletes key, for calls that PayChangu definitely rejected.
+	Release(ctx context.Context, key string) error
+
+	// Renew takes over a pending key whose earlier call is presumed
+	// abandoned. If the record for key is still pending and was last
+	// updated at updatedAt, Renew sets its UpdatedAt to the current
+	// time and reports true. It reports false if another caller has
+	// renewed or completed the key since it was read.
+	Renew(ctx context.Context, key string, updatedAt time.Time) (bool, error)
+}
+
+// WithIdempotencyStore guards InitiateMobileMoneyPayout, InitiateBankPayout
+// and InitiatePayment with store, keyed by ChargeID or TxRef:
+//
+//   - a repeated call returns the stored result instead of calling the API;
+//   - a repeated call made while the earlier one may still be in flight
+//     fails with ErrIdempotencyInProgress;
+//   - a repeated call whose earlier attempt has an unknown outcome (for
+//     example a timeout) and is older than the stale window is looked up
+//     as described for IsTransient. Payments have no such lookup and are
+//     sent again, which creates a second checkout session but moves no
+//     money by itself;
+//   - a repeated call with a different payload fails with
+//     ErrIdempotencyKeyReused.
+//
+// Calls rejected by PayChangu with a 4xx status, or by the PayoutGuard,
+// release their key, so they can be corrected and sent again.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(p *payChangu) {
+		p.idempotency = store
+	}
+}
+
+// WithIdempotencyStaleAfter sets how long a pending idempotency key is
+// assumed to be in flight, 5 minutes by default. It must be longer than
+// a call can take, retries included, or a slow call may be sent twice.
+func WithIdempotencyStaleAfter(d time.Duration) Option {
+	return func(p *payChangu) {
+		p.idempotencyStaleAfter = d
+	}
+}
+
+// Fingerprint returns the fingerprint of a request payload
+// as stored in IdempotencyRecord.Fingerprint.
+func Fingerprint(request any) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// idempotent runs send at most once per key of p's idempotency store.
+// check, if set, vets the call before it is sent; a failed check
+// releases the key. A repeated call returns the stored result, or fails
+// with ErrIdempotencyInProgress while the earlier call may be in flight.
+// Once it is stale, the key is renewed and reconcile looks the call up:
+// it returns nil, nil when PayChangu has no record of the earlier call,
+// in which case send is called again. A nil reconcile always sends again.
+func idempotent[T any](ctx context.Context, p *payChangu, key string, request any, check func() error, send func() (*T, error), reconcile func() (*T, error)) (*T, error) {
+	store := p.idempotency
+	if p.idempotencyScope != "" {
+		key = p.idempotencyScope + "/" + key
+	}
+	fingerprint, err := Fingerprint(request)
+	if err != nil {
+		return nil, err
+	}
+
+	record, created, err := store.Begin(ctx, key, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency store: %w", err)
+	}
+
+	if !created {
+		if record.Fingerprint != fingerprint {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, key)
+		}
+
+		if record.Status == IdempotencyCompleted {
+			var result T
+			if err := json.Unmarshal(record.Result, &result); err != nil {
+				return nil, fmt.Errorf("idempotency store: failed to decode result for %s: %w", key, err)
+			}
+			return &result, nil
+		}
+
+		staleAfter := p.idempotencyStaleAfter
+		if staleAfter <= 0 {
+			staleAfter = defaultIdempotencyStaleAfter
+		}
+		if time.Since(record.UpdatedAt) < staleAfter {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyInProgress, key)
+		}
+		renewed, err := store.Renew(ctx, key, record.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("idempotency store: %w", err)
+		}
+		if !renewed {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyInProgress, key)
+		}
+
+		if reconcile != nil {
+			result, err := reconcile()
+			if err != nil {
+				return nil, fmt.Errorf("failed to reconcile %s: %w", key, err)
+			}
+			if result != nil {
+				return result, complete(ctx, store, key, result)
+			}
+		}
+	}
+
+	if check != nil {
+		if err := check(); err != nil {
+			return nil, release(ctx, store, key, err)
+		}
+	}
+
+	result, err := send()
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+			return nil, release(ctx, store, key, err)
+		}
+		return nil, err
+	}
+
+	return result, complete(ctx, store, key, result)
+}
+
+// release deletes key after its call failed with err, which it returns
+// along with any error from the store.
+func release(ctx context.Context, store IdempotencyStore, key string, err error) error {
+	if releaseErr := store.Release(ctx, key); releaseErr != nil {
+		return errors.Join(err, fmt.Errorf("idempotency store: %w", releaseErr))
+	}
+	return err
+}
+
+// complete stores result as the outcome of key.
+func complete(ctx context.Context, store IdempotencyStore, key string, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("idempotency store: %w", err)
+	}
+	if err := store.Complete(ctx, key, data); err != nil {
+		return fmt.Errorf("idempotency store: %w", err)
+	}
+	return nil
+}
+
+// notFound reports whether err is an API response saying
+// that the requested transaction does not exist.
+func notFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps
+// records in memory. Records are lost when the process exits.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore creates an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+// Begin implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, key, fingerprint string) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok {
+		return &record, false, nil
+	}
+
+	now := time.Now()
+	record := IdempotencyRecord{Key: key, Fingerprint: fingerprint, Status: IdempotencyPending, CreatedAt: now, UpdatedAt: now}
+	s.records[key] = record
+	return &record, true, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, result []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return fmt.Errorf("no idempotency record for %s", key)
+	}
+	record.Status = IdempotencyCompleted
+	record.Result = result
+	record.UpdatedAt = time.Now()
+	s.records[key] = record
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Renew implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Renew(ctx context.Context, key string, updatedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || record.Status != IdempotencyPending || !record.UpdatedAt.Equal(updatedAt) {
+		return false, nil
+	}
+	record.UpdatedAt = time.Now()
+	s.records[key] = record
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package paychangu
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/santinalbrowns/paychangu/internal/sqlutil"
+)
+
+// SQLDialect selects the placeholder style used by the SQL stores.
+type SQLDialect int
+
+// Supported SQL dialects.
+const (
+	// DialectQuestion uses ? placeholders, as SQLite and MySQL do.
+	DialectQuestion SQLDialect = iota
+
+	// DialectDollar uses $1, $2, ... placeholders, as PostgreSQL does.
+	DialectDollar
+)
+
+// SQLIdempotencyStore is an IdempotencyStore backed by a database/sql
+// database, so records survive restarts and are shared between
+// processes. The caller opens db with the driver of their choice.
+//
+// Example Usage:
+//
+//	db, err := sql.Open("pgx", os.Getenv("DATABASE_URL"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	store := paychangu.NewSQLIdempotencyStore(db, paychangu.DialectDollar)
+//	if err := store.CreateTable(context.Background()); err != nil {
+//	    log.Fatal(err)
+//	}
+//	client := paychangu.New("your_secret_key", paychangu.WithIdempotencyStore(store))
+type SQLIdempotencyStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+
+	// Table is the name of the table records are kept in.
+	// It defaults to "paychangu_idempotency".
+	Table string
+}
+
+// NewSQLIdempotencyStore creates an SQLIdempotencyStore using db.
+func NewSQLIdempotencyStore(db *sql.DB, dialect SQLDialect) *SQLIdempotencyStore {
+	return &SQLIdempotencyStore{db: db, dialect: dialect, Table: "paychangu_idempotency"}
+}
+
+// CreateTable creates the table records are kept in, if it does not exist.
+func (s *SQLIdempotencyStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.Table+` (
+	idempotency_key VARCHAR(255) PRIMARY KEY,
+	fingerprint VARCHAR(64) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	result TEXT,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`)
+	return err
+}
+
+// Begin implements IdempotencyStore.
+func (s *SQLIdempotencyStore) Begin(ctx context.Context, key, fingerprint string) (*IdempotencyRecord, bool, error) {
+	record, err := s.get(ctx, key)
+	if err == nil {
+		return record, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	now := time.Now().UTC()
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+s.Table+`
+	(idempotency_key, fingerprint, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`),
+		key, fingerprint, IdempotencyPending, now, now)
+	if err != nil {
+		// Another process may have inserted the key since we looked.
+		if record, getErr := s.get(ctx, key); getErr == nil {
+			return record, false, nil
+		}
+		return nil, false, err
+	}
+
+	return &IdempotencyRecord{Key: key, Fingerprint: fingerprint, Status: IdempotencyPending, CreatedAt: now, UpdatedAt: now}, true, nil
+}
+
+// get loads the record for key, returning sql.ErrNoRows if there is none.
+func (s *SQLIdempotencyStore) get(ctx context.Context, key string) (*IdempotencyRecord, error) {
+	record := IdempotencyRecord{Key: key}
+	var result sql.NullString
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT fingerprint, status, result, created_at, updated_at
+	FROM `+s.Table+` WHERE idempotency_key = ?`), key).
+		Scan(&record.Fingerprint, &record.Status, &result, &record.CreatedAt, &record.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if result.Valid {
+		record.Result = []byte(result.String)
+	}
+	return &record, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *SQLIdempotencyStore) Complete(ctx context.Context, key string, result []byte) error {
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.Table+`
+	SET status = ?, result = ?, updated_at = ? WHERE idempotency_key = ?`),
+		IdempotencyCompleted, string(result), time.Now().UTC(), key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no idempotency record for %s", key)
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *SQLIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+s.Table+` WHERE idempotency_key = ?`), key)
+	return err
+}
+
+// Renew implements IdempotencyStore. The update only matches the row
+// read by the caller, so of several processes renewing a key one wins.
+func (s *SQLIdempotencyStore) Renew(ctx context.Context, key string, updatedAt time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.Table+`
+	SET updated_at = ? WHERE idempotency_key = ? AND status = ? AND updated_at = ?`),
+		time.Now().UTC(), key, IdempotencyPending, updatedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// rebind rewrites the ? placeholders in query for s's dialect.
+func (s *SQLIdempotencyStore) rebind(query string) string {
+	return sqlutil.Rebind(query, s.dialect == DialectDollar)
+}
@@ -1,6 +1,9 @@
 package paychangu
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // The Request struct is used to initiate a
 // payment request with PayChangu.
@@ -46,29 +49,27 @@ type Request struct {
 	// Example: "TX12345ABC"
 	TxRef string `json:"tx_ref"`
 
+	// CustomerID optionally links the payment
+	// to a customer created with CreateCustomer.
+	// Example: "CUS_8a7d2f"
+	CustomerID string `json:"customer_id,omitempty"`
+
 	// Customization provides a title and
 	// description for the payment, shown on the checkout page.
-	Customization struct {
-		// Title is the title for the payment,
-		// shown to the customer.
-		Title string `json:"title"`
+	Customization Customization `json:"customization"`
 
-		// Description gives a brief description of the payment.
-		Description string `json:"description"`
-	} `json:"customization"`
+	// Splits optionally divides the collected funds
+	// between sub-accounts created with CreateSubAccount.
+	// Whatever is not split off settles to the main account.
+	Splits []Split `json:"splits,omitempty"`
 
 	// Meta allows additional data to be passed
-	// with the transaction, such as a unique
-	// customer identifier.
-	Meta struct {
-		// UUID is a unique identifier
-		// associated with the transaction.
-		UUID string `json:"uuid"`
-
-		// Response can store any custom
-		// information for tracking.
-		Response string `json:"response"`
-	} `json:"meta"`
+	// with the transaction, such as order or
+	// tenant identifiers. It accepts a map or any
+	// JSON-marshalable value and is returned on
+	// PaymentDetails, where DecodeMeta reads it back.
+	// Example: map[string]string{"order_id": "ORD-1001"}
+	Meta any `json:"meta,omitempty"`
 }
 
 // The PayChanguResponse struct represents a
@@ -86,36 +87,44 @@ type Response struct {
 
 	// Data holds further details about
 	// the transaction, including the checkout URL.
-	Data struct {
-		// Event specifies the event type of the transaction.
-		Event string `json:"event"`
+	Data Checkout `json:"data"`
+}
 
-		// CheckoutURL is the URL to which the customer
-		// should be redirected to complete the payment.
-		CheckoutURL string `json:"checkout_url"`
+// The Checkout struct holds the checkout
+// URL returned for an initiated payment.
+type Checkout struct {
+	// Event specifies the event type of the transaction.
+	Event string `json:"event"`
 
-		// Data contains details such as transaction
-		// reference, currency, and amount.
-		Data struct {
-			// TxRef is the unique transaction
-			// reference from the request.
-			TxRef string `json:"tx_ref"`
+	// CheckoutURL is the URL to which the customer
+	// should be redirected to complete the payment.
+	CheckoutURL string `json:"checkout_url"`
 
-			// Currency indicates the transaction currency.
-			Currency string `json:"currency"`
+	// Data contains details such as transaction
+	// reference, currency, and amount.
+	Data CheckoutTransaction `json:"data"`
+}
+
+// The CheckoutTransaction struct summarizes
+// the transaction behind a checkout.
+type CheckoutTransaction struct {
+	// TxRef is the unique transaction
+	// reference from the request.
+	TxRef string `json:"tx_ref"`
+
+	// Currency indicates the transaction currency.
+	Currency string `json:"currency"`
 
-			// Amount specifies the transaction
-			// amount in the given currency.
-			Amount float64 `json:"amount"`
+	// Amount specifies the transaction
+	// amount in the given currency.
+	Amount float64 `json:"amount"`
 
-			// Mode describes the payment mode, e.g., "online".
-			Mode string `json:"mode"`
+	// Mode describes the payment mode, e.g., "online".
+	Mode string `json:"mode"`
 
-			// Status reflects the current status
-			// of the transaction, e.g., "pending".
-			Status string `json:"status"`
-		} `json:"data"`
-	} `json:"data"`
+	// Status reflects the current status
+	// of the transaction, e.g., "pending".
+	Status string `json:"status"`
 }
 
 // The Error struct is used to capture errors
@@ -190,9 +199,10 @@ type PaymentDetails struct {
 	// customization details for the transaction.
 	Customization Customization `json:"customization"`
 
-	// Meta stores any additional metadata
-	// associated with the transaction.
-	Meta interface{} `json:"meta"`
+	// Meta stores the raw metadata sent with
+	// Request.Meta. Use DecodeMeta to read it
+	// into a typed value.
+	Meta json.RawMessage `json:"meta"`
 
 	// Authorization contains payment authorization details.
 	Authorization PaymentAuthorization `json:"authorization"`
@@ -205,6 +215,10 @@ type PaymentDetails struct {
 	// to the payment processing steps.
 	Logs []PaymentLog `json:"logs"`
 
+	// Splits is the settlement breakdown per
+	// sub-account when the payment was split.
+	Splits []SplitBreakdown `json:"splits"`
+
 	// CreatedAt is the timestamp of when
 	// the payment was created.
 	CreatedAt time.Time `json:"created_at"`
@@ -226,7 +240,7 @@ type Customization struct {
 
 	// Logo is an optional URL to the logo
 	// displayed on the payment page.
-	Logo string `json:"logo"`
+	Logo string `json:"logo,omitempty"`
 }
 
 // The PaymentAuthorization struct captures
@@ -265,6 +279,10 @@ type PaymentAuthorization struct {
 // information about the customer involved
 // in the payment.
 type CustomerInfo struct {
+	// ID is the customer ID, present when the
+	// payment was linked to a stored customer.
+	ID string `json:"customer_id,omitempty"`
+
 	// Email is the customer's email address.
 	Email string `json:"email"`
 
@@ -293,22 +311,25 @@ type PaymentLog struct {
 
 // MobileMoneyOperator represents a supported mobile money operator.
 type MobileMoneyOperator struct {
-	ID                  int     `json:"id"`
-	Name                string  `json:"name"`
-	RefID               string  `json:"ref_id"`
-	LiveMode            int     `json:"live_mode"`
-	ShortCode           string  `json:"short_code"`
-	Logo                *string `json:"logo"` // Can be null
-	OperatorFee         string  `json:"operator_fee"`
-	PaymentPercentFee   string  `json:"payment_percent_fee"`
-	PaymentFiatFee      *string `json:"payment_fiat_fee"`   // Can be null
-	PayoutPercentFee    *string `json:"payout_percent_fee"` // Can be null
-	PayoutFiatFee       *string `json:"payout_fiat_fee"`    // Can be null
-	SupportsWithdrawals bool    `json:"supports_withdrawals"`
-	SupportedCountry    struct {
-		Name     string `json:"name"`
-		Currency string `json:"currency"`
-	} `json:"supported_country"`
+	ID                  int              `json:"id"`
+	Name                string           `json:"name"`
+	RefID               string           `json:"ref_id"`
+	LiveMode            int              `json:"live_mode"`
+	ShortCode           string           `json:"short_code"`
+	Logo                *string          `json:"logo"` // Can be null
+	OperatorFee         string           `json:"operator_fee"`
+	PaymentPercentFee   string           `json:"payment_percent_fee"`
+	PaymentFiatFee      *string          `json:"payment_fiat_fee"`   // Can be null
+	PayoutPercentFee    *string          `json:"payout_percent_fee"` // Can be null
+	PayoutFiatFee       *string          `json:"payout_fiat_fee"`    // Can be null
+	SupportsWithdrawals bool             `json:"supports_withdrawals"`
+	SupportedCountry    SupportedCountry `json:"supported_country"`
+}
+
+// SupportedCountry is the country and currency served by a mobile money operator.
+type SupportedCountry struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
 }
 
 // MobileMoneyOperatorsResponse is the response structure for fetching mobile money operators.
@@ -324,6 +345,7 @@ type MobileMoneyPayoutRequest struct {
 	MobileMoneyOperatorRefID string  `json:"mobile_money_operator_ref_id"`
 	Amount                   float64 `json:"amount"` // Use float64 for amount
 	ChargeID                 string  `json:"charge_id"`
+	CustomerID               string  `json:"customer_id,omitempty"`        // Optional
 	Email                    string  `json:"email,omitempty"`              // Optional
 	FirstName                string  `json:"first_name,omitempty"`         // Optional
 	LastName                 string  `json:"last_name,omitempty"`          // Optional
@@ -332,42 +354,51 @@ type MobileMoneyPayoutRequest struct {
 
 // PayoutTransactionDetails represents the details of a payout transaction.
 type PayoutTransactionDetails struct {
-	ChargeID    string    `json:"charge_id"`
-	RefID       string    `json:"ref_id"`
-	TransID     *string   `json:"trans_id"` // Can be null
-	Currency    string    `json:"currency"`
-	Amount      float64   `json:"amount"`
-	FirstName   *string   `json:"first_name"` // Can be null
-	LastName    *string   `json:"last_name"`  // Can be null
-	Email       *string   `json:"email"`      // Can be null
-	Type        string    `json:"type"`
-	TraceID     *string   `json:"trace_id"` // Can be null
-	Status      string    `json:"status"`
-	Mobile      string    `json:"mobile"`
-	Attempts    int       `json:"attempts"`
-	Mode        string    `json:"mode"`
-	CreatedAt   time.Time `json:"created_at"`
-	CompletedAt time.Time `json:"completed_at"`
-	EventType   string    `json:"event_type"`
-	MobileMoney struct {
-		Name    string `json:"name"`
-		RefID   string `json:"ref_id"`
-		Country string `json:"country"`
-	} `json:"mobile_money"`
-	TransactionCharges struct {
-		Currency string `json:"currency"`
-		Amount   string `json:"amount"` // The API returns this as a string, e.g., "1.7"
-	} `json:"transaction_charges"`
-	Customer *interface{} `json:"customer"` // Can be null or an object
+	ChargeID           string             `json:"charge_id"`
+	RefID              string             `json:"ref_id"`
+	TransID            *string            `json:"trans_id"` // Can be null
+	Currency           string             `json:"currency"`
+	Amount             float64            `json:"amount"`
+	FirstName          *string            `json:"first_name"` // Can be null
+	LastName           *string            `json:"last_name"`  // Can be null
+	Email              *string            `json:"email"`      // Can be null
+	Type               string             `json:"type"`
+	TraceID            *string            `json:"trace_id"` // Can be null
+	Status             string             `json:"status"`
+	Mobile             string             `json:"mobile"`
+	Attempts           int                `json:"attempts"`
+	Mode               string             `json:"mode"`
+	CreatedAt          time.Time          `json:"created_at"`
+	CompletedAt        time.Time          `json:"completed_at"`
+	EventType          string             `json:"event_type"`
+	MobileMoney        PayoutMobileMoney  `json:"mobile_money"`
+	TransactionCharges TransactionCharges `json:"transaction_charges"`
+	Customer           *interface{}       `json:"customer"` // Can be null or an object
+}
+
+// PayoutMobileMoney identifies the mobile money operator that handled a payout.
+type PayoutMobileMoney struct {
+	Name    string `json:"name"`
+	RefID   string `json:"ref_id"`
+	Country string `json:"country"`
+}
+
+// TransactionCharges represents the fees charged on a payout.
+type TransactionCharges struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"` // The API returns this as a string, e.g., "1.7"
 }
 
 // MobileMoneyPayoutResponse is the response for a successful mobile money payout initialization.
 type MobileMoneyPayoutResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Data    struct {
-		Transaction PayoutTransactionDetails `json:"transaction"`
-	} `json:"data"`
+	Status  string                `json:"status"`
+	Message string                `json:"message"`
+	Data    MobileMoneyPayoutData `json:"data"`
+}
+
+// MobileMoneyPayoutData wraps the transaction created by a mobile money payout.
+type MobileMoneyPayoutData struct {
+	Transaction PayoutTransactionDetails `json:"transaction"`
 }
 
 // MobileMoneyPayoutErrorResponse is the response for a failed mobile money payout.
@@ -405,9 +436,10 @@ type BankPayoutRequest struct {
 	ChargeID          string  `json:"charge_id"`
 	BankAccountName   string  `json:"bank_account_name"`
 	BankAccountNumber string  `json:"bank_account_number"`
-	Email             string  `json:"email,omitempty"`      // Optional
-	FirstName         string  `json:"first_name,omitempty"` // Optional
-	LastName          string  `json:"last_name,omitempty"`  // Optional
+	CustomerID        string  `json:"customer_id,omitempty"` // Optional
+	Email             string  `json:"email,omitempty"`       // Optional
+	FirstName         string  `json:"first_name,omitempty"`  // Optional
+	LastName          string  `json:"last_name,omitempty"`   // Optional
 }
 
 // RecipientAccountDetails represents the bank account details of the recipient for a bank payout.
@@ -421,27 +453,24 @@ type RecipientAccountDetails struct {
 // BankPayoutTransactionDetails represents the detailed transaction information for a bank payout.
 // This structure is similar to PayoutTransactionDetails but includes specific bank recipient details.
 type BankPayoutTransactionDetails struct {
-	ChargeID           string     `json:"charge_id"`
-	RefID              string     `json:"ref_id"`
-	TransID            *string    `json:"trans_id"` // Can be null
-	Currency           string     `json:"currency"`
-	Amount             float64    `json:"amount"`
-	FirstName          *string    `json:"first_name"` // Can be null
-	LastName           *string    `json:"last_name"`  // Can be null
-	Email              *string    `json:"email"`      // Can be null
-	Type               string     `json:"type"`
-	TraceID            *string    `json:"trace_id"` // Can be null
-	Status             string     `json:"status"`
-	Mobile             string     `json:"mobile"` // API returns "0" for bank payouts, but still present
-	Attempts           int        `json:"attempts"`
-	Mode               string     `json:"mode"`
-	CreatedAt          time.Time  `json:"created_at"`
-	CompletedAt        *time.Time `json:"completed_at"` // Can be null
-	EventType          string     `json:"event_type"`
-	TransactionCharges struct {
-		Currency string `json:"currency"`
-		Amount   string `json:"amount"`
-	} `json:"transaction_charges"`
+	ChargeID                string                  `json:"charge_id"`
+	RefID                   string                  `json:"ref_id"`
+	TransID                 *string                 `json:"trans_id"` // Can be null
+	Currency                string                  `json:"currency"`
+	Amount                  float64                 `json:"amount"`
+	FirstName               *string                 `json:"first_name"` // Can be null
+	LastName                *string                 `json:"last_name"`  // Can be null
+	Email                   *string                 `json:"email"`      // Can be null
+	Type                    string                  `json:"type"`
+	TraceID                 *string                 `json:"trace_id"` // Can be null
+	Status                  string                  `json:"status"`
+	Mobile                  string                  `json:"mobile"` // API returns "0" for bank payouts, but still present
+	Attempts                int                     `json:"attempts"`
+	Mode                    string                  `json:"mode"`
+	CreatedAt               time.Time               `json:"created_at"`
+	CompletedAt             *time.Time              `json:"completed_at"` // Can be null
+	EventType               string                  `json:"event_type"`
+	TransactionCharges      TransactionCharges      `json:"transaction_charges"`
 	RecipientAccountDetails RecipientAccountDetails `json:"recipient_account_details"` // New field for bank payouts
 	// Note: mobile_money field from PayoutTransactionDetails is not present here.
 	// This makes it distinct from MobileMoneyPayoutTransactionDetails.
@@ -449,11 +478,14 @@ type BankPayoutTransactionDetails struct {
 
 // BankPayoutResponse is the response for a successful bank payout initialization.
 type BankPayoutResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Data    struct {
-		Transaction BankPayoutTransactionDetails `json:"transaction"`
-	} `json:"data"`
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    BankPayoutData `json:"data"`
+}
+
+// BankPayoutData wraps the transaction created by a bank payout.
+type BankPayoutData struct {
+	Transaction BankPayoutTransactionDetails `json:"transaction"`
 }
 
 // GetBankPayoutDetailsResponse is the response structure for fetching bank payout details.
@@ -462,3 +494,156 @@ type GetBankPayoutDetailsResponse struct {
 	Message string                       `json:"message"`
 	Data    BankPayoutTransactionDetails `json:"data"` // Reusing the existing BankPayoutTransactionDetails struct
 }
+
+////////Customers////////
+
+// CustomerRequest is the payload for creating or updating a customer.
+type CustomerRequest struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"` // Optional
+	Phone     string `json:"phone,omitempty"`     // Optional
+}
+
+// Customer represents a customer profile stored with PayChangu.
+type Customer struct {
+	ID        string    `json:"customer_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Phone     *string   `json:"phone"` // Can be null
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Transactions is only populated by GetCustomer.
+	Transactions []PaymentDetails `json:"transactions,omitempty"`
+}
+
+// CustomerResponse is the response structure for a single customer.
+type CustomerResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Data    Customer `json:"data"`
+}
+
+// CustomersResponse is the response structure for listing customers.
+type CustomersResponse struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message"`
+	Data    []Customer `json:"data"`
+}
+
+////////Virtual Accounts////////
+
+// VirtualAccountRequest is the payload for creating a virtual (collection) bank account.
+type VirtualAccountRequest struct {
+	AccountName string  `json:"account_name"`
+	Currency    string  `json:"currency"`
+	CustomerID  string  `json:"customer_id,omitempty"` // Optional, for a dedicated account per customer
+	TxRef       string  `json:"tx_ref,omitempty"`      // Optional, for a single-use account per invoice
+	Amount      float64 `json:"amount,omitempty"`      // Optional, expected amount for a single-use account
+}
+
+// VirtualAccount represents a bank account number that customers
+// can transfer funds into. Incoming transfers are reported as
+// PaymentDetails and can be verified with VerifyPayment.
+type VirtualAccount struct {
+	ID            string     `json:"account_id"`
+	AccountNumber string     `json:"account_number"`
+	AccountName   string     `json:"account_name"`
+	BankName      string     `json:"bank_name"`
+	Currency      string     `json:"currency"`
+	CustomerID    *string    `json:"customer_id"` // Can be null
+	TxRef         *string    `json:"tx_ref"`      // Can be null
+	Amount        *float64   `json:"amount"`      // Can be null
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     *time.Time `json:"expires_at"` // Can be null
+}
+
+// VirtualAccountResponse is the response structure for a single virtual account.
+type VirtualAccountResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    VirtualAccount `json:"data"`
+}
+
+// VirtualAccountTransfersResponse is the response structure for listing
+// the transfers received into a virtual account.
+type VirtualAccountTransfersResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    []PaymentDetails `json:"data"`
+}
+
+////////Split Payments////////
+
+// Split types accepted in Split.Type.
+const (
+	// SplitTypePercentage sends Value percent of the amount to the sub-account.
+	SplitTypePercentage = "percentage"
+
+	// SplitTypeFlat sends a fixed Value in the payment currency to the sub-account.
+	SplitTypeFlat = "flat"
+)
+
+// Split describes the share of a payment that settles to a sub-account.
+type Split struct {
+	SubAccountID string  `json:"subaccount_id"`
+	Type         string  `json:"split_type"` // SplitTypePercentage or SplitTypeFlat
+	Value        float64 `json:"split_value"`
+	// BearsFees charges the transaction fees to this sub-account.
+	// When no split bears fees, they are charged to the main account.
+	BearsFees bool `json:"bears_fees,omitempty"`
+}
+
+// SplitBreakdown reports how a verified payment was settled to a sub-account.
+type SplitBreakdown struct {
+	SubAccountID string  `json:"subaccount_id"`
+	Type         string  `json:"split_type"`
+	Value        float64 `json:"split_value"`
+	Currency     string  `json:"currency"`
+	Amount       float64 `json:"amount"`  // Amount settled to the sub-account
+	Charges      float64 `json:"charges"` // Fees deducted from this share
+}
+
+// SubAccountRequest is the payload for creating or updating a sub-account.
+type SubAccountRequest struct {
+	BusinessName      string  `json:"business_name"`
+	BankUUID          string  `json:"bank_uuid"` // From GetSupportedBanks
+	BankAccountName   string  `json:"bank_account_name"`
+	BankAccountNumber string  `json:"bank_account_number"`
+	Currency          string  `json:"currency"`
+	SplitType         string  `json:"split_type,omitempty"`  // Optional, default for payments without explicit splits
+	SplitValue        float64 `json:"split_value,omitempty"` // Optional
+	Email             string  `json:"email,omitempty"`       // Optional
+}
+
+// SubAccount represents a sub-merchant that can receive a share of payments.
+type SubAccount struct {
+	ID                string    `json:"subaccount_id"`
+	BusinessName      string    `json:"business_name"`
+	BankUUID          string    `json:"bank_uuid"`
+	BankName          string    `json:"bank_name"`
+	BankAccountName   string    `json:"bank_account_name"`
+	BankAccountNumber string    `json:"bank_account_number"`
+	Currency          string    `json:"currency"`
+	SplitType         *string   `json:"split_type"`  // Can be null
+	SplitValue        *float64  `json:"split_value"` // Can be null
+	Email             *string   `json:"email"`       // Can be null
+	Active            bool      `json:"active"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// SubAccountResponse is the response structure for a single sub-account.
+type SubAccountResponse struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message"`
+	Data    SubAccount `json:"data"`
+}
+
+// SubAccountsResponse is the response structure for listing sub-accounts.
+type SubAccountsResponse struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Data    []SubAccount `json:"data"`
+}
@@ -0,0 +1,28 @@
+// Package sqlutil holds the helpers shared by the SDK's SQL stores.
+package sqlutil
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rebind rewrites the ? placeholders in query as $1, $2, ...
+// when dollar is set, as PostgreSQL expects, and returns
+// query unchanged otherwise.
+func Rebind(query string, dollar bool) string {
+	if !dollar {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package paychangu
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// alwaysRedacted lists the JSON fields that are masked in
+// logged bodies regardless of the fields given to WithBodyLogging.
+var alwaysRedacted = []string{"card_number", "account_number", "bank_account_number"}
+
+// DefaultRedactedFields lists the additional JSON fields masked in
+// logged bodies when WithBodyLogging is called without its own list.
+var DefaultRedactedFields = []string{"mobile", "mobile_number", "phone"}
+
+// WithLogger logs every API call to logger with its method, path, status,
+// latency, tx_ref or charge_id and retry count. Headers are never logged,
+// so the secret key does not reach the logs. It is shorthand for adding
+// LoggingMiddleware after any middlewares given with WithMiddleware.
+//
+// Example Usage:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+//	client := paychangu.New("your_secret_key", paychangu.WithLogger(logger))
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *payChangu) {
+		p.logger = logger
+	}
+}
+
+// WithBodyLogging adds request and response bodies to the records written
+// by WithLogger, masking the given JSON fields. See LogOptions.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key",
+//	    paychangu.WithLogger(logger),
+//	    paychangu.WithBodyLogging("mobile", "email"),
+//	)
+func WithBodyLogging(redactFields ...string) Option {
+	return func(p *payChangu) {
+		p.logOptions = LogOptions{LogBodies: true, RedactFields: redactFields}
+	}
+}
+
+// LogOptions configures LoggingMiddleware.
+type LogOptions struct {
+	// LogBodies adds request and response bodies to each record.
+	// Non-JSON bodies are left out.
+	LogBodies bool
+
+	// RedactFields lists the JSON fields whose values are masked in
+	// logged bodies, keeping only their last four characters. Card and
+	// account numbers are always masked. DefaultRedactedFields is used
+	// when RedactFields is empty.
+	RedactFields []string
+}
+
+// LoggingMiddleware logs every API call to logger with its method, path,
+// status, latency, tx_ref or charge_id and retry count. Headers are never
+// logged, so the secret key does not reach the logs.
+func LoggingMiddleware(logger *slog.Logger, opts LogOptions) Middleware {
+	var redact map[string]bool
+	if opts.LogBodies {
+		fields := opts.RedactFields
+		if len(fields) == 0 {
+			fields = DefaultRedactedFields
+		}
+		redact = make(map[string]bool, len(alwaysRedacted)+len(fields))
+		for _, field := range append(alwaysRedacted, fields...) {
+			redact[strings.ToLower(field)] = true
+		}
+	}
+
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			var reqBody []byte
+			if redact != nil && req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					reqBody, _ = io.ReadAll(body)
+					body.Close()
+				}
+			}
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			latency := time.Since(start)
+
+			ctx := req.Context()
+			op, _ := OperationFromContext(ctx)
+			attrs := []slog.Attr{
+				slog.String("operation", op.Name),
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Duration("latency", latency),
+				slog.Int("retries", StatsFromContext(ctx).Retries),
+			}
+			if op.TxRef != "" {
+				attrs = append(attrs, slog.String("tx_ref", op.TxRef))
+			}
+			if op.ChargeID != "" {
+				attrs = append(attrs, slog.String("charge_id", op.ChargeID))
+			}
+
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelError, "paychangu request failed", attrs...)
+				return resp, err
+			}
+
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			if redact != nil {
+				if body := redactBody(reqBody, redact); body != "" {
+					attrs = append(attrs, slog.String("request_body", body))
+				}
+				if body := redactBody(peekBody(resp), redact); body != "" {
+					attrs = append(attrs, slog.String("response_body", body))
+				}
+			}
+
+			level := slog.LevelInfo
+			if resp.StatusCode >= http.StatusBadRequest {
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(ctx, level, "paychangu request", attrs...)
+
+			return resp, err
+		})
+	}
+}
+
+// peekBody reads the response body and puts
+// it back so the caller can still decode it.
+func peekBody(resp *http.Response) []byte {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// redactBody returns body as compact JSON with the redact
+// fields masked, or "" if body is empty or not JSON.
+func redactBody(body []byte, redact map[string]bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return ""
+	}
+
+	out, err := json.Marshal(redactValue(v, redact))
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// redactValue masks the redact fields anywhere in a decoded JSON value.
+func redactValue(v any, redact map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if redact[strings.ToLower(key)] && val != nil {
+				if s, ok := val.(string); ok {
+					v[key] = mask(s)
+				} else {
+					v[key] = "****"
+				}
+				continue
+			}
+			v[key] = redactValue(val, redact)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i], redact)
+		}
+	}
+	return v
+}
+
+// mask hides all but the last four characters of s.
+func mask(s string) string {
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
@@ -0,0 +1,59 @@
+package paychangu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeMeta decodes the metadata returned on a payment into a value of
+// type T, so the metadata sent with Request.Meta round-trips with its type.
+// It returns the zero value of T when the payment carries no metadata.
+//
+// Example Usage:
+//
+//	type OrderMeta struct {
+//	    OrderID  string `json:"order_id"`
+//	    TenantID string `json:"tenant_id"`
+//	}
+//
+//	request.Meta = OrderMeta{OrderID: "ORD-1001", TenantID: "acme"}
+//	// ...
+//	verifyResp, err := client.VerifyPayment(request.TxRef)
+//	meta, err := paychangu.DecodeMeta[OrderMeta](verifyResp.Data)
+//	if err != nil {
+//	    log.Fatalf("Failed to decode metadata: %v", err)
+//	}
+//	fmt.Println("Order:", meta.OrderID)
+func DecodeMeta[T any](details PaymentDetails) (T, error) {
+	var meta T
+
+	raw := bytes.TrimSpace(details.Meta)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return meta, nil
+	}
+
+	// The API may echo metadata back as a JSON-encoded string
+	// rather than an object, in which case it is unwrapped first.
+	// Strings holding anything but an object or array, such as "42",
+	// are metadata that was sent as a string and are left alone.
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return meta, fmt.Errorf("failed to decode meta: %w", err)
+		}
+		if s == "" {
+			return meta, nil
+		}
+		inner := bytes.TrimSpace([]byte(s))
+		if len(inner) > 0 && (inner[0] == '{' || inner[0] == '[') && json.Valid(inner) {
+			raw = inner
+		}
+	}
+
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return meta, fmt.Errorf("failed to decode meta: %w", err)
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,34 @@
+package paychangu
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeMeta(t *testing.T) {
+	type orderMeta struct {
+		OrderID string `json:"order_id"`
+	}
+	for _, raw := range []string{`{"order_id":"ORD-1001"}`, `"{\"order_id\":\"ORD-1001\"}"`} {
+		meta, err := DecodeMeta[orderMeta](PaymentDetails{Meta: json.RawMessage(raw)})
+		if err != nil || meta.OrderID != "ORD-1001" {
+			t.Errorf("DecodeMeta(%s) = %+v, %v", raw, meta, err)
+		}
+	}
+
+	// Strings are only unwrapped when they hold an object or array.
+	for raw, want := range map[string]string{`"42"`: "42", `"true"`: "true", `"ORD-1001"`: "ORD-1001", `""`: ""} {
+		meta, err := DecodeMeta[string](PaymentDetails{Meta: json.RawMessage(raw)})
+		if err != nil || meta != want {
+			t.Errorf("DecodeMeta[string](%s) = %q, %v, want %q", raw, meta, err, want)
+		}
+	}
+
+	ids, err := DecodeMeta[[]int](PaymentDetails{Meta: json.RawMessage(`"[1, 2]"`)})
+	if err != nil || len(ids) != 2 {
+		t.Errorf("DecodeMeta[[]int] = %v, %v", ids, err)
+	}
+	if meta, err := DecodeMeta[orderMeta](PaymentDetails{}); err != nil || meta.OrderID != "" {
+		t.Errorf("DecodeMeta without metadata = %+v, %v", meta, err)
+	}
+}
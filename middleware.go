@@ -0,0 +1,129 @@
+package paychangu
+
+import (
+	"context"
+	"net/http"
+)
+
+// A Doer sends an HTTP request to the PayChangu API. *http.Client is a Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts an ordinary function to the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// A Middleware wraps the Doer that sends each API call, for example to add
+// tracing or metrics. The request context carries the Operation being
+// performed; see OperationFromContext.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware adds middlewares around every API call. The first
+// middleware is the outermost one. Middlewares added this way run
+// outside those installed by WithLogger and WithRetries, so they see
+// each call once however many times it is retried; add RetryMiddleware
+// here instead to place middlewares inside the retries.
+//
+// Example Usage:
+//
+//	audit := func(next paychangu.Doer) paychangu.Doer {
+//	    return paychangu.DoerFunc(func(req *http.Request) (*http.Response, error) {
+//	        op, _ := paychangu.OperationFromContext(req.Context())
+//	        log.Printf("calling %s", op.Name)
+//	        return next.Do(req)
+//	    })
+//	}
+//	client := paychangu.New("your_secret_key", paychangu.WithMiddleware(audit))
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(p *payChangu) {
+		p.middlewares = append(p.middlewares, middlewares...)
+	}
+}
+
+// Operation describes the API call a request belongs to.
+type Operation struct {
+	// Name is the client method, e.g. "InitiatePayment".
+	Name string
+
+	// TxRef is the payment reference, if any.
+	TxRef string
+
+	// ChargeID is the payout reference, if any.
+	ChargeID string
+
+	// Currency of the amount being moved, if known.
+	Currency string
+
+	// Amount being collected or paid out, if any.
+	Amount float64
+}
+
+// operationKey is the context key for the current Operation.
+type operationKey struct{}
+
+// OperationFromContext returns the Operation carried by the
+// context of a request sent by the client, if any.
+func OperationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(Operation)
+	return op, ok
+}
+
+// CallStats reports what happened while a call was being sent.
+type CallStats struct {
+	// Retries is the number of times the call was retried.
+	Retries int
+
+	// RateLimited is the number of 429 responses received for the call.
+	RateLimited int
+}
+
+// statsKey is the context key for the *CallStats of the current call.
+type statsKey struct{}
+
+// StatsFromContext returns the stats of the call carried by ctx.
+// Middlewares read it after next.Do returns to see, for example,
+// how many times the call was retried.
+func StatsFromContext(ctx context.Context) CallStats {
+	if stats, ok := ctx.Value(statsKey{}).(*CallStats); ok {
+		return *stats
+	}
+	return CallStats{}
+}
+
+// callStats returns the mutable stats of the call carried by ctx.
+func callStats(ctx context.Context) *CallStats {
+	if stats, ok := ctx.Value(statsKey{}).(*CallStats); ok {
+		return stats
+	}
+	return &CallStats{}
+}
+
+// chain builds the Doer that sends requests through the configured
+// middlewares, then logging, the circuit breaker, retries and rate
+// limiting, and finally the HTTP client.
+func (p *payChangu) chain() Doer {
+	middlewares := append([]Middleware(nil), p.middlewares...)
+	if p.logger != nil {
+		middlewares = append(middlewares, LoggingMiddleware(p.logger, p.logOptions))
+	}
+	if p.breaker != nil {
+		middlewares = append(middlewares, p.breaker.Middleware())
+	}
+	if p.maxRetries > 0 {
+		middlewares = append(middlewares, RetryMiddleware(p.maxRetries))
+	}
+	if p.limiter != nil {
+		middlewares = append(middlewares, p.limiter.Middleware())
+	}
+
+	var doer Doer = DoerFunc(p.send)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		doer = middlewares[i](doer)
+	}
+	return doer
+}
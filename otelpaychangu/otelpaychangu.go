@@ -0,0 +1,193 @@
+// Package otelpaychangu instruments the PayChangu client with
+// OpenTelemetry. It creates a span per API call, injects the trace
+// context into outbound headers, and records request counts and
+// latency histograms.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key",
+//	    paychangu.WithMiddleware(otelpaychangu.Middleware()),
+//	)
+//
+// The global tracer provider, meter provider and propagator are used
+// unless others are given with WithTracerProvider, WithMeterProvider
+// and WithPropagators, which makes the middleware testable with the
+// in-memory exporters from the OpenTelemetry SDK.
+package otelpaychangu
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package as the
+// instrumentation scope of its tracer and meter.
+const instrumentationName = "github.com/santinalbrowns/paychangu/otelpaychangu"
+
+// Attribute keys set on spans and metrics.
+const (
+	OperationKey    = attribute.Key("paychangu.operation")
+	CurrencyKey     = attribute.Key("paychangu.currency")
+	AmountBucketKey = attribute.Key("paychangu.amount_bucket")
+	TxRefKey        = attribute.Key("paychangu.tx_ref")
+	ChargeIDKey     = attribute.Key("paychangu.charge_id")
+)
+
+// config holds the providers used by the middleware.
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// An Option configures the middleware.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record metrics.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// WithPropagators sets the propagator used to inject
+// the trace context into outbound request headers.
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = p
+	}
+}
+
+// Middleware returns a paychangu.Middleware that traces and measures
+// every API call. Spans are named after the client method, e.g.
+// "paychangu.InitiatePayment", and carry the endpoint, status code,
+// currency and an amount bucket. The metrics recorded are
+// paychangu.client.requests (a counter) and
+// paychangu.client.duration (a histogram, in seconds).
+func Middleware(opts ...Option) paychangu.Middleware {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+	meter := c.meterProvider.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("paychangu.client.requests",
+		metric.WithDescription("Number of PayChangu API calls."),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	duration, err := meter.Float64Histogram("paychangu.client.duration",
+		metric.WithDescription("Duration of PayChangu API calls, including retries."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(next paychangu.Doer) paychangu.Doer {
+		return paychangu.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op, _ := paychangu.OperationFromContext(req.Context())
+			name := op.Name
+			if name == "" {
+				name = req.Method
+			}
+
+			attrs := []attribute.KeyValue{
+				OperationKey.String(name),
+				attribute.String("http.request.method", req.Method),
+			}
+			if op.Currency != "" {
+				attrs = append(attrs, CurrencyKey.String(op.Currency))
+			}
+			if op.Amount > 0 {
+				attrs = append(attrs, AmountBucketKey.String(AmountBucket(op.Amount)))
+			}
+
+			ctx, span := tracer.Start(req.Context(), "paychangu."+name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+			// The path carries the TxRef or ChargeID, so it is kept off
+			// the metrics, where every payment would be a new series.
+			span.SetAttributes(attribute.String("url.path", req.URL.Path))
+			if op.TxRef != "" {
+				span.SetAttributes(TxRefKey.String(op.TxRef))
+			}
+			if op.ChargeID != "" {
+				span.SetAttributes(ChargeIDKey.String(op.ChargeID))
+			}
+
+			req = req.WithContext(ctx)
+			c.propagators.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			elapsed := time.Since(start).Seconds()
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				attrs = append(attrs, attribute.String("error.type", "transport"))
+			} else {
+				span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+				if resp.StatusCode >= http.StatusBadRequest {
+					span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+					attrs = append(attrs, attribute.String("error.type", strconv.Itoa(resp.StatusCode)))
+				}
+				attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+			}
+
+			set := metric.WithAttributes(attrs...)
+			if requests != nil {
+				requests.Add(ctx, 1, set)
+			}
+			if duration != nil {
+				duration.Record(ctx, elapsed, set)
+			}
+
+			return resp, err
+		})
+	}
+}
+
+// AmountBucket groups an amount into a coarse, low-cardinality
+// range suitable for span and metric attributes.
+func AmountBucket(amount float64) string {
+	switch {
+	case amount < 1_000:
+		return "<1k"
+	case amount < 10_000:
+		return "1k-10k"
+	case amount < 100_000:
+		return "10k-100k"
+	case amount < 1_000_000:
+		return "100k-1M"
+	default:
+		return ">=1M"
+	}
+}
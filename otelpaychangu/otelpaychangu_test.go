@@ -0,0 +1,168 @@
+package otelpaychangu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/santinalbrowns/paychangu"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"status":"success","message":"Hosted payment session generated successfully.","data":{"checkout_url":"https://checkout.paychangu.com/1"}}`)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client := paychangu.New("SEC-TEST-key",
+		paychangu.WithBaseURL(server.URL),
+		paychangu.WithMiddleware(Middleware(
+			WithTracerProvider(tp),
+			WithMeterProvider(mp),
+			WithPropagators(propagation.TraceContext{}),
+		)),
+	)
+	request := paychangu.NewPaymentRequest(2500, "MWK").WithTxRef("TX-1")
+	if _, err := client.InitiatePayment(request); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "paychangu.InitiatePayment" {
+		t.Errorf("span name = %q, want %q", span.Name, "paychangu.InitiatePayment")
+	}
+	if span.Status.Code == codes.Error {
+		t.Errorf("span status = %v, want unset", span.Status)
+	}
+	want := map[attribute.Key]attribute.Value{
+		OperationKey:                attribute.StringValue("InitiatePayment"),
+		CurrencyKey:                 attribute.StringValue("MWK"),
+		AmountBucketKey:             attribute.StringValue("1k-10k"),
+		TxRefKey:                    attribute.StringValue("TX-1"),
+		"http.request.method":       attribute.StringValue(http.MethodPost),
+		"url.path":                  attribute.StringValue("/payment"),
+		"http.response.status_code": attribute.IntValue(http.StatusCreated),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		got[kv.Key] = kv.Value
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("span attribute %s = %v, want %v", key, got[key].Emit(), value.Emit())
+		}
+	}
+	if traceparent == "" {
+		t.Error("trace context was not injected into the request headers")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var requests int64
+	var durations uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				if m.Name != "paychangu.client.requests" {
+					continue
+				}
+				for _, dp := range data.DataPoints {
+					if op, _ := dp.Attributes.Value(OperationKey); op.AsString() != "InitiatePayment" {
+						t.Errorf("request counted for operation %q", op.AsString())
+					}
+					if status, _ := dp.Attributes.Value("http.response.status_code"); status.AsInt64() != http.StatusCreated {
+						t.Errorf("request counted with status code %d", status.AsInt64())
+					}
+					if path, ok := dp.Attributes.Value("url.path"); ok {
+						t.Errorf("request counted with path %q, which makes a series per payment", path.AsString())
+					}
+					requests += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				if m.Name != "paychangu.client.duration" {
+					continue
+				}
+				for _, dp := range data.DataPoints {
+					durations += dp.Count
+				}
+			}
+		}
+	}
+	if requests != 1 {
+		t.Errorf("paychangu.client.requests = %d, want 1", requests)
+	}
+	if durations != 1 {
+		t.Errorf("paychangu.client.duration count = %d, want 1", durations)
+	}
+}
+
+func TestMiddlewareError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Invalid currency"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client := paychangu.New("SEC-TEST-key",
+		paychangu.WithBaseURL(server.URL),
+		paychangu.WithMiddleware(Middleware(WithTracerProvider(tp), WithMeterProvider(mp))),
+	)
+	if _, err := client.InitiatePayment(paychangu.NewPaymentRequest(2500, "XYZ")); err == nil {
+		t.Fatal("expected the payment to fail")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("span status = %v, want error", spans[0].Status)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var errorType string
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if data, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "paychangu.client.requests" {
+				for _, dp := range data.DataPoints {
+					value, _ := dp.Attributes.Value("error.type")
+					errorType = value.AsString()
+				}
+			}
+		}
+	}
+	if errorType != "400" {
+		t.Errorf("error.type = %q, want %q", errorType, "400")
+	}
+}
@@ -0,0 +1,287 @@
+// Package outbox makes PayChangu calls reliable with respect to your
+// own database. Instead of calling InitiatePayment after committing an
+// order, and losing the call if the process dies in between, write the
+// intended operation into the same database transaction as the order.
+// A Relay then executes it against the client, records the outcome and
+// publishes it to a Sink.
+//
+// Example Usage:
+//
+//	box := outbox.New(db, paychangu.DialectDollar)
+//	if err := box.CreateTable(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	tx, err := db.BeginTx(ctx, nil)
+//	// ... insert the order with tx
+//	txRef, err := box.EnqueuePayment(ctx, tx, paymentReq)
+//	err = tx.Commit()
+//
+//	relay := &outbox.Relay{Outbox: box, Client: client, Sink: sink}
+//	go relay.Run(ctx)
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/internal/sqlutil"
+)
+
+// ErrNotFound is returned when there is no message with the given ID.
+var ErrNotFound = errors.New("outbox: message not found")
+
+// Operation is the PayChangu operation of a message.
+type Operation string
+
+// Operations.
+const (
+	OpPayment           Operation = "payment"
+	OpMobileMoneyPayout Operation = "mobile_money_payout"
+	OpBankPayout        Operation = "bank_payout"
+)
+
+// Status is the status of a message.
+type Status string
+
+// Message statuses.
+const (
+	// StatusPending messages wait to be executed, or to be
+	// retried after a transient failure.
+	StatusPending Status = "pending"
+
+	// StatusSucceeded messages were accepted by PayChangu.
+	StatusSucceeded Status = "succeeded"
+
+	// StatusFailed messages were rejected, or still failing
+	// after every attempt.
+	StatusFailed Status = "failed"
+)
+
+// Message is an operation written to the outbox.
+type Message struct {
+	// ID is the TxRef of a payment or the ChargeID of a payout.
+	ID        string
+	Operation Operation
+
+	// Payload is the request, as JSON.
+	Payload json.RawMessage
+
+	Status   Status
+	Attempts int
+
+	// Result is the response of PayChangu, as JSON, once the
+	// message succeeded.
+	Result json.RawMessage
+
+	// Error is the error of the last attempt, if it failed.
+	Error string
+
+	// NextAttemptAt is when a pending message is next executed.
+	NextAttemptAt time.Time
+
+	// PublishedAt is when the outcome was published to the Sink,
+	// or the zero time if it was not yet.
+	PublishedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Execer executes a statement. It is satisfied by *sql.Tx, as well as
+// *sql.DB and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Outbox is a table of messages in a database/sql database, such as
+// SQLite (e.g. with modernc.org/sqlite) or PostgreSQL (e.g. with pgx).
+// The caller opens db with the driver of their choice and passes the
+// matching dialect.
+type Outbox struct {
+	db      *sql.DB
+	dialect paychangu.SQLDialect
+
+	// Table is the name of the table messages are kept in.
+	// It defaults to "paychangu_outbox".
+	Table string
+}
+
+// New creates an Outbox using db.
+func New(db *sql.DB, dialect paychangu.SQLDialect) *Outbox {
+	return &Outbox{db: db, dialect: dialect, Table: "paychangu_outbox"}
+}
+
+// CreateTable creates the table messages are kept in, if it does not exist.
+// Times the relay compares are kept as Unix milliseconds, so that they
+// compare correctly whatever the driver does with time.Time.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	_, err := o.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+o.Table+` (
+	id VARCHAR(255) PRIMARY KEY,
+	operation VARCHAR(32) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INTEGER NOT NULL,
+	result TEXT,
+	last_error TEXT,
+	next_attempt_at BIGINT NOT NULL,
+	locked_until BIGINT NOT NULL,
+	published_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = o.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS `+o.Table+`_due ON `+o.Table+` (status, next_attempt_at)`)
+	return err
+}
+
+// EnqueuePayment writes a payment to the outbox within tx and returns
+// its TxRef, which is generated if request has none.
+func (o *Outbox) EnqueuePayment(ctx context.Context, tx Execer, request paychangu.Request) (string, error) {
+	if request.TxRef == "" {
+		request.TxRef = paychangu.NewReference("TX")
+	}
+	return request.TxRef, o.enqueue(ctx, tx, request.TxRef, OpPayment, request)
+}
+
+// EnqueueMobileMoneyPayout writes a mobile money payout to the outbox
+// within tx and returns its ChargeID, which is generated if request has none.
+func (o *Outbox) EnqueueMobileMoneyPayout(ctx context.Context, tx Execer, request paychangu.MobileMoneyPayoutRequest) (string, error) {
+	if request.ChargeID == "" {
+		request.ChargeID = paychangu.NewReference("PAYOUT")
+	}
+	return request.ChargeID, o.enqueue(ctx, tx, request.ChargeID, OpMobileMoneyPayout, request)
+}
+
+// EnqueueBankPayout writes a bank payout to the outbox within tx and
+// returns its ChargeID, which is generated if request has none.
+func (o *Outbox) EnqueueBankPayout(ctx context.Context, tx Execer, request paychangu.BankPayoutRequest) (string, error) {
+	if request.ChargeID == "" {
+		request.ChargeID = paychangu.NewReference("PAYOUT")
+	}
+	return request.ChargeID, o.enqueue(ctx, tx, request.ChargeID, OpBankPayout, request)
+}
+
+// enqueue inserts a pending message.
+func (o *Outbox) enqueue(ctx context.Context, tx Execer, id string, op Operation, request any) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, o.rebind(`INSERT INTO `+o.Table+`
+	(id, operation, payload, status, attempts, next_attempt_at, locked_until, created_at, updated_at)
+	VALUES (?, ?, ?, ?, 0, ?, 0, ?, ?)`),
+		id, op, string(payload), StatusPending, now.UnixMilli(), now, now)
+	return err
+}
+
+// Get returns the message with the given ID, or ErrNotFound.
+func (o *Outbox) Get(ctx context.Context, id string) (*Message, error) {
+	m := Message{ID: id}
+	var payload string
+	var result, lastError sql.NullString
+	var published sql.NullTime
+	var next int64
+	err := o.db.QueryRowContext(ctx, o.rebind(`SELECT operation, payload, status, attempts, result, last_error,
+	next_attempt_at, published_at, created_at, updated_at FROM `+o.Table+` WHERE id = ?`), id).
+		Scan(&m.Operation, &payload, &m.Status, &m.Attempts, &result, &lastError,
+			&next, &published, &m.CreatedAt, &m.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.Payload = json.RawMessage(payload)
+	if result.Valid && result.String != "" {
+		m.Result = json.RawMessage(result.String)
+	}
+	m.Error = lastError.String
+	m.NextAttemptAt = time.UnixMilli(next).UTC()
+	if published.Valid {
+		m.PublishedAt = published.Time
+	}
+	return &m, nil
+}
+
+// claim locks up to limit messages that are due to be executed or
+// published, until lease from now, and returns them.
+func (o *Outbox) claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Message, error) {
+	rows, err := o.db.QueryContext(ctx, o.rebind(`SELECT id FROM `+o.Table+`
+	WHERE locked_until < ? AND ((status = ? AND next_attempt_at <= ?) OR (status <> ? AND published_at IS NULL))
+	ORDER BY next_attempt_at LIMIT `+fmt.Sprint(limit)),
+		now.UnixMilli(), StatusPending, now.UnixMilli(), StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	for _, id := range ids {
+		// Another relay may have claimed the message since we looked.
+		res, err := o.db.ExecContext(ctx, o.rebind(`UPDATE `+o.Table+`
+		SET locked_until = ? WHERE id = ? AND locked_until < ?`),
+			now.Add(lease).UnixMilli(), id, now.UnixMilli())
+		if err != nil {
+			return messages, err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			continue
+		}
+		m, err := o.Get(ctx, id)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// begin records the start of an attempt at m.
+func (o *Outbox) begin(ctx context.Context, m *Message) error {
+	m.Attempts++
+	m.UpdatedAt = time.Now().UTC()
+	_, err := o.db.ExecContext(ctx, o.rebind(`UPDATE `+o.Table+`
+	SET attempts = ?, updated_at = ? WHERE id = ?`), m.Attempts, m.UpdatedAt, m.ID)
+	return err
+}
+
+// record saves the outcome of an attempt at m and releases its lock.
+func (o *Outbox) record(ctx context.Context, m *Message) error {
+	m.UpdatedAt = time.Now().UTC()
+	var published any
+	if !m.PublishedAt.IsZero() {
+		published = m.PublishedAt
+	}
+	_, err := o.db.ExecContext(ctx, o.rebind(`UPDATE `+o.Table+`
+	SET status = ?, result = ?, last_error = ?, next_attempt_at = ?, locked_until = 0, published_at = ?, updated_at = ?
+	WHERE id = ?`),
+		m.Status, string(m.Result), m.Error, m.NextAttemptAt.UnixMilli(), published, m.UpdatedAt, m.ID)
+	return err
+}
+
+// rebind rewrites the ? placeholders in query for o's dialect.
+func (o *Outbox) rebind(query string) string {
+	return sqlutil.Rebind(query, o.dialect == paychangu.DialectDollar)
+}
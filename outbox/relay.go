@@ -0,0 +1,303 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Client is the part of the PayChangu client used by a Relay.
+type Client interface {
+	InitiatePaymentContext(ctx context.Context, request paychangu.Request) (*paychangu.Response, error)
+	InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error)
+	InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error)
+	GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error)
+	GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error)
+}
+
+// Event is the outcome of a message, published to a Sink.
+type Event struct {
+	// MessageID is the TxRef or ChargeID of the message. Events are
+	// delivered at least once, so consumers should use it to ignore
+	// duplicates.
+	MessageID string          `json:"message_id"`
+	Operation Operation       `json:"operation"`
+	Status    Status          `json:"status"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Attempts  int             `json:"attempts"`
+	At        time.Time       `json:"at"`
+}
+
+// Sink receives the outcomes of messages, e.g. to put them on a queue.
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, event Event) error
+
+// Publish implements Sink.
+func (f SinkFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Relay executes the messages of an Outbox and publishes their outcomes.
+// Several relays may share an outbox: each message is locked by the relay
+// that claims it for Lease.
+//
+// A relay that dies during a call leaves the outcome unknown, so the
+// next attempt at a payout looks it up first, as described for
+// paychangu.IsTransient. Payments cannot be looked up this way: a retried payment initiates a second checkout session for the
+// same TxRef, and the checkout URL of the first is lost. This moves no
+// money by itself, since the customer pays through one checkout only,
+// but verify by TxRef rather than by checkout. A client configured with
+// paychangu.WithIdempotencyStore replays the response of a payment it
+// received, and only initiates it again once the record is stale.
+type Relay struct {
+	// Outbox holds the messages. It is required.
+	Outbox *Outbox
+
+	// Client executes the messages. It is required.
+	Client Client
+
+	// Sink, if set, receives the outcome of every message.
+	Sink Sink
+
+	// MaxAttempts is the number of attempts at a message failing with a
+	// network error, a 429 or a 5xx status before it is failed. It
+	// defaults to 10.
+	MaxAttempts int
+
+	// RetryDelay is the wait before the second attempt, doubled for each
+	// attempt after it, up to an hour. It defaults to 30 seconds.
+	RetryDelay time.Duration
+
+	// Lease is how long a claimed message is locked. It defaults to
+	// five minutes and must be longer than a call to PayChangu takes.
+	Lease time.Duration
+
+	// BatchSize is the most messages claimed by a pass. It defaults to 100.
+	BatchSize int
+
+	// Interval is the time between passes of Run. It defaults to five seconds.
+	Interval time.Duration
+
+	// Logger, if set, receives the errors of individual messages,
+	// which do not stop the relay.
+	Logger *slog.Logger
+}
+
+// Run makes a pass every Interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Pass(ctx); err != nil && ctx.Err() == nil && r.Logger != nil {
+			r.Logger.Error("outbox relay pass failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass claims the messages that are due, executes the pending ones and
+// publishes the outcomes not yet published. It returns the errors of
+// the messages it could not process, joined.
+func (r *Relay) Pass(ctx context.Context) error {
+	lease := r.Lease
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+	batch := r.BatchSize
+	if batch <= 0 {
+		batch = 100
+	}
+
+	messages, err := r.Outbox.claim(ctx, time.Now(), lease, batch)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := r.process(ctx, m); err != nil {
+			if r.Logger != nil {
+				r.Logger.Warn("outbox message not processed", "id", m.ID, "operation", m.Operation, "error", err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// process executes m if it is pending, then publishes its outcome if it
+// is final, and records the result.
+func (r *Relay) process(ctx context.Context, m *Message) error {
+	if m.Status == StatusPending {
+		if err := r.Outbox.begin(ctx, m); err != nil {
+			return err
+		}
+		r.execute(ctx, m)
+	}
+
+	var publishErr error
+	if m.Status != StatusPending {
+		if r.Sink == nil {
+			m.PublishedAt = time.Now().UTC()
+		} else if publishErr = r.publish(ctx, m); publishErr == nil {
+			m.PublishedAt = time.Now().UTC()
+		}
+	}
+
+	if err := r.Outbox.record(ctx, m); err != nil {
+		return errors.Join(publishErr, err)
+	}
+	return publishErr
+}
+
+// execute makes an attempt at m and sets its status, result and error.
+func (r *Relay) execute(ctx context.Context, m *Message) {
+	result, err := r.attempt(ctx, m)
+	if err == nil {
+		m.Status = StatusSucceeded
+		m.Result = result
+		m.Error = ""
+		return
+	}
+
+	m.Result = result
+	m.Error = err.Error()
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	if !paychangu.IsTransient(err) || m.Attempts >= maxAttempts {
+		m.Status = StatusFailed
+		return
+	}
+
+	delay := r.RetryDelay
+	if delay <= 0 {
+		delay = 30 * time.Second
+	}
+	for i := 1; i < m.Attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	m.NextAttemptAt = time.Now().Add(delay)
+}
+
+// attempt executes m and returns the response as JSON. Payouts that an
+// earlier attempt may have sent are looked up first, and fail if
+// PayChangu reports them failed or reversed; payments are initiated
+// again.
+func (r *Relay) attempt(ctx context.Context, m *Message) (json.RawMessage, error) {
+	var response any
+	var err error
+	switch m.Operation {
+	case OpPayment:
+		var request paychangu.Request
+		if err := json.Unmarshal(m.Payload, &request); err != nil {
+			return nil, fmt.Errorf("outbox: invalid payload of %s: %w", m.ID, err)
+		}
+		response, err = r.Client.InitiatePaymentContext(ctx, request)
+
+	case OpMobileMoneyPayout:
+		if m.Attempts > 1 {
+			details, lookupErr := r.Client.GetMobileMoneyPayoutDetailsContext(ctx, m.ID)
+			if lookupErr == nil {
+				return found(details, details.Status)
+			}
+			if !notFound(lookupErr) {
+				return nil, lookupErr
+			}
+		}
+		var request paychangu.MobileMoneyPayoutRequest
+		if err := json.Unmarshal(m.Payload, &request); err != nil {
+			return nil, fmt.Errorf("outbox: invalid payload of %s: %w", m.ID, err)
+		}
+		response, err = r.Client.InitiateMobileMoneyPayoutContext(ctx, request)
+
+	case OpBankPayout:
+		if m.Attempts > 1 {
+			details, lookupErr := r.Client.GetBankPayoutDetailsContext(ctx, m.ID)
+			if lookupErr == nil {
+				return found(details, details.Status)
+			}
+			if !notFound(lookupErr) {
+				return nil, lookupErr
+			}
+		}
+		var request paychangu.BankPayoutRequest
+		if err := json.Unmarshal(m.Payload, &request); err != nil {
+			return nil, fmt.Errorf("outbox: invalid payload of %s: %w", m.ID, err)
+		}
+		response, err = r.Client.InitiateBankPayoutContext(ctx, request)
+
+	default:
+		return nil, fmt.Errorf("outbox: unknown operation %q of %s", m.Operation, m.ID)
+	}
+	return marshal(response, err)
+}
+
+// found returns the details of a payout made by an earlier attempt as
+// JSON, with an error if PayChangu reports that it failed or was
+// reversed.
+func found(details any, status string) (json.RawMessage, error) {
+	result, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	switch status := paychangu.NormalizeStatus(status); status {
+	case paychangu.StatusFailed, paychangu.StatusReversed:
+		return result, fmt.Errorf("outbox: payout %s", status)
+	}
+	return result, nil
+}
+
+// marshal returns response as JSON, or err.
+func marshal(response any, err error) (json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(response)
+}
+
+// notFound reports whether err is a 404 from the API.
+func notFound(err error) bool {
+	var apiErr *paychangu.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// publish sends the outcome of m to the Sink.
+func (r *Relay) publish(ctx context.Context, m *Message) error {
+	return r.Sink.Publish(ctx, Event{
+		MessageID: m.ID,
+		Operation: m.Operation,
+		Status:    m.Status,
+		Result:    m.Result,
+		Error:     m.Error,
+		Attempts:  m.Attempts,
+		At:        m.UpdatedAt,
+	})
+}
@@ -0,0 +1,273 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Client is the part of the PayChangu client used by a Machine.
+type Client interface {
+	InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error)
+	InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error)
+	GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error)
+	GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error)
+}
+
+// Machine moves payouts through their states, saving each transition to
+// Store before acting on it. Because a payout is saved as submitted
+// before the initiate call is made, a crash during the call leaves it
+// submitted, and Refresh later finds out whether PayChangu received it.
+//
+// Every call to PayChangu is preceded by a Store.Update, whose version
+// check lets only one of several machines sharing a store make it.
+type Machine struct {
+	// Store persists the payouts. It is required.
+	Store Store
+
+	// Client sends payouts to PayChangu. It is required.
+	Client Client
+
+	// ResendAfter is how long a submitted payout is left alone before
+	// Refresh may send it again, so that a call still in flight, or
+	// one PayChangu has not recorded yet, is not duplicated. It
+	// defaults to 5 minutes and should exceed the client's timeout.
+	ResendAfter time.Duration
+
+	// OnTransition, if set, is called after each transition is saved.
+	OnTransition func(ctx context.Context, p *Payout, t Transition)
+}
+
+// DraftMobileMoney saves a new mobile money payout as a draft. A
+// ChargeID is generated with paychangu.NewReference if request has none.
+func (m *Machine) DraftMobileMoney(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*Payout, error) {
+	if request.ChargeID == "" {
+		request.ChargeID = paychangu.NewReference("PAYOUT")
+	}
+	return m.draft(ctx, &Payout{ID: request.ChargeID, Kind: KindMobileMoney, MobileMoney: &request})
+}
+
+// DraftBank saves a new bank payout as a draft. A ChargeID is
+// generated with paychangu.NewReference if request has none.
+func (m *Machine) DraftBank(ctx context.Context, request paychangu.BankPayoutRequest) (*Payout, error) {
+	if request.ChargeID == "" {
+		request.ChargeID = paychangu.NewReference("PAYOUT")
+	}
+	return m.draft(ctx, &Payout{ID: request.ChargeID, Kind: KindBank, Bank: &request})
+}
+
+// draft saves p as a new draft.
+func (m *Machine) draft(ctx context.Context, p *Payout) (*Payout, error) {
+	now := time.Now().UTC()
+	p.State = StateDraft
+	p.History = []Transition{}
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	if err := m.Store.Create(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Approve moves a draft payout to approved, clearing it to be submitted.
+func (m *Machine) Approve(ctx context.Context, id, approver string) (*Payout, error) {
+	p, err := m.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return p, m.move(ctx, p, StateApproved, "approved by "+approver)
+}
+
+// Cancel fails a payout that has not been submitted yet.
+func (m *Machine) Cancel(ctx context.Context, id, reason string) (*Payout, error) {
+	p, err := m.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.State != StateDraft && p.State != StateApproved {
+		return nil, &TransitionError{ID: p.ID, From: p.State, To: StateFailed}
+	}
+	return p, m.move(ctx, p, StateFailed, reason)
+}
+
+// Submit sends an approved payout to PayChangu. If the outcome of the
+// call is unknown, the payout stays submitted and the error is returned;
+// Refresh resolves it later.
+func (m *Machine) Submit(ctx context.Context, id string) (*Payout, error) {
+	p, err := m.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.move(ctx, p, StateSubmitted, "submitted"); err != nil {
+		return nil, err
+	}
+	return p, m.send(ctx, p)
+}
+
+// send calls the initiate endpoint for a submitted payout
+// and moves it according to the response.
+func (m *Machine) send(ctx context.Context, p *Payout) error {
+	p.Attempts++
+
+	var refID, status string
+	var err error
+	switch p.Kind {
+	case KindMobileMoney:
+		var resp *paychangu.MobileMoneyPayoutResponse
+		if resp, err = m.Client.InitiateMobileMoneyPayoutContext(ctx, *p.MobileMoney); err == nil {
+			refID, status = resp.Data.Transaction.RefID, resp.Data.Transaction.Status
+		}
+	case KindBank:
+		var resp *paychangu.BankPayoutResponse
+		if resp, err = m.Client.InitiateBankPayoutContext(ctx, *p.Bank); err == nil {
+			refID, status = resp.Data.Transaction.RefID, resp.Data.Transaction.Status
+		}
+	default:
+		err = fmt.Errorf("unknown payout kind %q", p.Kind)
+	}
+
+	if err != nil {
+		p.LastError = err.Error()
+		if errors.Is(err, paychangu.ErrPayoutRefused) {
+			// The payout guard refused the payout, so it was not made
+			// and must not be sent again.
+			if moveErr := m.move(ctx, p, StateFailed, "refused: "+err.Error()); moveErr != nil {
+				return errors.Join(err, moveErr)
+			}
+			return err
+		}
+		var apiErr *paychangu.APIError
+		if errors.As(err, &apiErr) && !paychangu.IsTransient(err) {
+			// PayChangu rejected the payout, so it was not made.
+			if moveErr := m.move(ctx, p, StateFailed, "rejected: "+err.Error()); moveErr != nil {
+				return errors.Join(err, moveErr)
+			}
+			return err
+		}
+		if saveErr := m.save(ctx, p); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		return err
+	}
+
+	p.RefID = refID
+	p.LastError = ""
+	state, ok := stateOf(status)
+	if !ok {
+		state = StatePending
+	}
+	return m.move(ctx, p, state, "initiated with status "+status)
+}
+
+// Refresh checks a submitted or pending payout with the payout details
+// endpoint and moves it to the state PayChangu reports. A submitted
+// payout that PayChangu has no record of is sent again once it has
+// been submitted for longer than ResendAfter. It is claimed first, so
+// if another machine resends it at the same time one of them fails
+// with ErrConflict.
+func (m *Machine) Refresh(ctx context.Context, id string) (*Payout, error) {
+	p, err := m.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.State != StateSubmitted && p.State != StatePending {
+		return p, nil
+	}
+
+	var refID, status string
+	switch p.Kind {
+	case KindMobileMoney:
+		var d *paychangu.PayoutTransactionDetails
+		if d, err = m.Client.GetMobileMoneyPayoutDetailsContext(ctx, p.ID); err == nil {
+			refID, status = d.RefID, d.Status
+		}
+	case KindBank:
+		var d *paychangu.BankPayoutTransactionDetails
+		if d, err = m.Client.GetBankPayoutDetailsContext(ctx, p.ID); err == nil {
+			refID, status = d.RefID, d.Status
+		}
+	default:
+		return nil, fmt.Errorf("unknown payout kind %q", p.Kind)
+	}
+
+	var apiErr *paychangu.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && p.State == StateSubmitted {
+		if !m.resendable(p) {
+			return p, nil
+		}
+		if err := m.save(ctx, p); err != nil {
+			return nil, err
+		}
+		return p, m.send(ctx, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if refID != "" {
+		p.RefID = refID
+	}
+	return p, m.apply(ctx, p, status, "details report "+status)
+}
+
+// resendable reports whether a submitted payout has waited
+// ResendAfter since it was last saved.
+func (m *Machine) resendable(p *Payout) bool {
+	after := m.ResendAfter
+	if after <= 0 {
+		after = 5 * time.Minute
+	}
+	return time.Since(p.UpdatedAt) >= after
+}
+
+// HandleWebhook applies a payout webhook to the payout with its ChargeID.
+// It can be used as a paychangu.WebhookHandler. Events for unknown
+// payouts are ignored.
+func (m *Machine) HandleWebhook(ctx context.Context, event *paychangu.WebhookEvent) error {
+	if event.ChargeID == "" {
+		return nil
+	}
+	p, err := m.Store.Get(ctx, event.ChargeID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return m.apply(ctx, p, event.Status, "webhook reports "+event.Status)
+}
+
+// apply moves p to the state matching a PayChangu status. Unknown
+// statuses, the current state and stale updates, such as a pending
+// report for a payout already known to be successful, are ignored.
+func (m *Machine) apply(ctx context.Context, p *Payout, status, reason string) error {
+	state, ok := stateOf(status)
+	if !ok || state == p.State || !CanTransition(p.State, state) {
+		return nil
+	}
+	return m.move(ctx, p, state, reason)
+}
+
+// move transitions p to state and saves it.
+func (m *Machine) move(ctx context.Context, p *Payout, state State, reason string) error {
+	if err := p.transition(state, reason); err != nil {
+		return err
+	}
+	if err := m.save(ctx, p); err != nil {
+		return err
+	}
+	if m.OnTransition != nil {
+		m.OnTransition(ctx, p, p.History[len(p.History)-1])
+	}
+	return nil
+}
+
+// save writes p to the store.
+func (m *Machine) save(ctx context.Context, p *Payout) error {
+	p.UpdatedAt = time.Now().UTC()
+	return m.Store.Update(ctx, p)
+}
@@ -0,0 +1,63 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// refuseAll is a paychangu.PayoutGuard that refuses every payout.
+type refuseAll struct{}
+
+func (refuseAll) CheckPayout(ctx context.Context, payout paychangu.PayoutCheck) error {
+	return fmt.Errorf("%w: recipient %s is blocked", paychangu.ErrPayoutRefused, payout.Recipient)
+}
+
+func TestGuardRefusalFailsPayout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := paychangu.New("sec-test-key", paychangu.WithBaseURL(server.URL), paychangu.WithPayoutGuard(refuseAll{}))
+	machine := &Machine{Store: NewMemoryStore(), Client: client, ResendAfter: -1}
+
+	p, err := machine.DraftMobileMoney(ctx, paychangu.MobileMoneyPayoutRequest{Mobile: "0991234567", Amount: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := machine.Approve(ctx, p.ID, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := machine.Submit(ctx, p.ID); !errors.Is(err, paychangu.ErrPayoutRefused) {
+		t.Fatalf("Submit: err = %v, want ErrPayoutRefused", err)
+	}
+
+	p, err = machine.Store.Get(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != StateFailed {
+		t.Fatalf("state = %s, want failed", p.State)
+	}
+
+	// Nothing is left to send.
+	if err := (&Worker{Machine: machine}).Pass(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := machine.Refresh(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p.Attempts != 1 || calls.Load() != 0 {
+		t.Errorf("attempts = %d with %d API calls, want 1 and none", p.Attempts, calls.Load())
+	}
+}
@@ -0,0 +1,198 @@
+// Package payout models PayChangu payouts as a persisted workflow:
+//
+//	draft → approved → submitted → pending → successful / failed
+//	                                          successful → reversed
+//
+// A Machine drives payouts through these states with the payout calls,
+// the payout details endpoints and webhooks, saving every transition to
+// a Store. A Worker submits approved payouts and resumes the ones a
+// restart left in flight.
+//
+// Example Usage:
+//
+//	store := payout.NewSQLStore(db, paychangu.DialectQuestion)
+//	machine := &payout.Machine{Store: store, Client: client}
+//
+//	p, err := machine.DraftMobileMoney(ctx, paychangu.NewMobileMoneyPayoutRequest(mobile, operator, 5000))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	machine.Approve(ctx, p.ID, "jane@example.com")
+//
+//	worker := &payout.Worker{Machine: machine, Interval: time.Minute}
+//	go worker.Run(ctx)
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// State is the state of a payout.
+type State string
+
+// Payout states.
+const (
+	// StateDraft is a payout that was created but not yet approved.
+	StateDraft State = "draft"
+
+	// StateApproved is a payout cleared to be sent to PayChangu.
+	StateApproved State = "approved"
+
+	// StateSubmitted is a payout whose initiate call was started but
+	// whose outcome is not yet known, e.g. because the call timed out.
+	StateSubmitted State = "submitted"
+
+	// StatePending is a payout PayChangu accepted and is processing.
+	StatePending State = "pending"
+
+	// StateSuccessful is a payout that reached the recipient.
+	StateSuccessful State = "successful"
+
+	// StateFailed is a payout that PayChangu rejected or could not complete.
+	StateFailed State = "failed"
+
+	// StateReversed is a successful payout that was later reversed.
+	StateReversed State = "reversed"
+)
+
+// transitions lists the states each state may move to.
+var transitions = map[State][]State{
+	StateDraft:      {StateApproved, StateFailed},
+	StateApproved:   {StateSubmitted, StateFailed},
+	StateSubmitted:  {StatePending, StateSuccessful, StateFailed},
+	StatePending:    {StateSuccessful, StateFailed},
+	StateSuccessful: {StateReversed},
+}
+
+// Final reports whether s is a state payouts never leave,
+// apart from a successful payout being reversed.
+func (s State) Final() bool {
+	return s == StateSuccessful || s == StateFailed || s == StateReversed
+}
+
+// CanTransition reports whether a payout in state from may move to state to.
+func CanTransition(from, to State) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returned by Store implementations and the Machine.
+var (
+	// ErrNotFound is returned when a payout does not exist.
+	ErrNotFound = errors.New("payout: not found")
+
+	// ErrConflict is returned when a payout was changed by
+	// someone else since it was read.
+	ErrConflict = errors.New("payout: concurrent update")
+)
+
+// TransitionError is returned when a payout is asked to
+// move to a state it cannot reach from its current one.
+type TransitionError struct {
+	ID   string
+	From State
+	To   State
+}
+
+// Error implements the error interface.
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("payout %s: cannot move from %s to %s", e.ID, e.From, e.To)
+}
+
+// Kind is the kind of a payout.
+type Kind string
+
+// Payout kinds.
+const (
+	KindMobileMoney Kind = "mobile_money"
+	KindBank        Kind = "bank"
+)
+
+// Transition is an entry in the history of a payout.
+type Transition struct {
+	From   State     `json:"from"`
+	To     State     `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Payout is a payout and its progress through the workflow.
+type Payout struct {
+	// ID is the ChargeID of the payout.
+	ID   string `json:"id"`
+	Kind Kind   `json:"kind"`
+
+	// MobileMoney is the request of a KindMobileMoney payout.
+	MobileMoney *paychangu.MobileMoneyPayoutRequest `json:"mobile_money,omitempty"`
+
+	// Bank is the request of a KindBank payout.
+	Bank *paychangu.BankPayoutRequest `json:"bank,omitempty"`
+
+	State State `json:"state"`
+
+	// RefID is PayChangu's reference for the payout, once known.
+	RefID string `json:"ref_id,omitempty"`
+
+	// Attempts is the number of times the payout was submitted.
+	Attempts int `json:"attempts"`
+
+	// LastError is the error of the last failed call, if any.
+	LastError string `json:"last_error,omitempty"`
+
+	// History holds every transition, oldest first.
+	History []Transition `json:"history"`
+
+	// Version is incremented by the Store on every update
+	// and used to detect concurrent updates.
+	Version int `json:"version"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Amount returns the amount of the payout.
+func (p *Payout) Amount() float64 {
+	if p.Bank != nil {
+		return p.Bank.Amount
+	}
+	if p.MobileMoney != nil {
+		return p.MobileMoney.Amount
+	}
+	return 0
+}
+
+// transition moves p to state to, recording why.
+func (p *Payout) transition(to State, reason string) error {
+	if !CanTransition(p.State, to) {
+		return &TransitionError{ID: p.ID, From: p.State, To: to}
+	}
+	now := time.Now().UTC()
+	p.History = append(p.History, Transition{From: p.State, To: to, At: now, Reason: reason})
+	p.State = to
+	p.UpdatedAt = now
+	return nil
+}
+
+// stateOf maps a PayChangu transaction status to a payout state.
+func stateOf(status string) (State, bool) {
+	switch paychangu.NormalizeStatus(status) {
+	case paychangu.StatusSuccess:
+		return StateSuccessful, true
+	case paychangu.StatusFailed:
+		return StateFailed, true
+	case paychangu.StatusReversed:
+		return StateReversed, true
+	case paychangu.StatusPending:
+		return StatePending, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,139 @@
+package payout
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/internal/sqlutil"
+)
+
+// SQLStore is a Store backed by a database/sql database, such as
+// SQLite (e.g. with modernc.org/sqlite) or PostgreSQL (e.g. with pgx).
+// The caller opens db with the driver of their choice and passes the
+// matching dialect.
+type SQLStore struct {
+	db      *sql.DB
+	dialect paychangu.SQLDialect
+
+	// Table is the name of the table payouts are kept in.
+	// It defaults to "paychangu_payouts".
+	Table string
+}
+
+// NewSQLStore creates an SQLStore using db.
+func NewSQLStore(db *sql.DB, dialect paychangu.SQLDialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect, Table: "paychangu_payouts"}
+}
+
+// CreateTable creates the table payouts are kept in, if it does not exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.Table+` (
+	id VARCHAR(255) PRIMARY KEY,
+	kind VARCHAR(32) NOT NULL,
+	state VARCHAR(32) NOT NULL,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS `+s.Table+`_state ON `+s.Table+` (state)`)
+	return err
+}
+
+// Create implements Store.
+func (s *SQLStore) Create(ctx context.Context, p *Payout) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+s.Table+`
+	(id, kind, state, version, data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		p.ID, p.Kind, p.State, p.Version, string(data), p.CreatedAt, p.UpdatedAt)
+	return err
+}
+
+// Get implements Store.
+func (s *SQLStore) Get(ctx context.Context, id string) (*Payout, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT data FROM `+s.Table+` WHERE id = ?`), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Payout
+	return &p, json.Unmarshal([]byte(data), &p)
+}
+
+// Update implements Store.
+func (s *SQLStore) Update(ctx context.Context, p *Payout) error {
+	p.Version++
+	data, err := json.Marshal(p)
+	if err != nil {
+		p.Version--
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.Table+`
+	SET state = ?, version = ?, data = ?, updated_at = ? WHERE id = ? AND version = ?`),
+		p.State, p.Version, string(data), p.UpdatedAt, p.ID, p.Version-1)
+	if err != nil {
+		p.Version--
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		p.Version--
+		if _, err := s.Get(ctx, p.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrConflict, p.ID)
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *SQLStore) List(ctx context.Context, states ...State) ([]*Payout, error) {
+	if len(states) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(states))
+	for i, state := range states {
+		args[i] = state
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(states)), ", ")
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT data FROM `+s.Table+`
+	WHERE state IN (`+placeholders+`) ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*Payout
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var p Payout
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, &p)
+	}
+	return payouts, rows.Err()
+}
+
+// rebind rewrites the ? placeholders in query for s's dialect.
+func (s *SQLStore) rebind(query string) string {
+	return sqlutil.Rebind(query, s.dialect == paychangu.DialectDollar)
+}
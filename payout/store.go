@@ -0,0 +1,119 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Store persists payouts. Implementations must be safe for concurrent use.
+type Store interface {
+	// Create saves a new payout. It fails if the ID is already used.
+	Create(ctx context.Context, p *Payout) error
+
+	// Get returns the payout with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Payout, error)
+
+	// Update saves p if its Version matches the stored one, and
+	// increments p.Version. Otherwise it returns ErrConflict.
+	Update(ctx context.Context, p *Payout) error
+
+	// List returns the payouts in any of the given states,
+	// oldest first.
+	List(ctx context.Context, states ...State) ([]*Payout, error)
+}
+
+// MemoryStore is a Store that keeps payouts in memory, for tests
+// and single-process use. Payouts are lost when the process exits.
+type MemoryStore struct {
+	mu      sync.Mutex
+	payouts map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{payouts: make(map[string][]byte)}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(ctx context.Context, p *Payout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.payouts[p.ID]; ok {
+		return fmt.Errorf("payout %s already exists", p.ID)
+	}
+	return s.put(p)
+}
+
+// put stores a copy of p. s.mu must be held.
+func (s *MemoryStore) put(p *Payout) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	s.payouts[p.ID] = data
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.payouts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	var p Payout
+	return &p, json.Unmarshal(data, &p)
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(ctx context.Context, p *Payout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.payouts[p.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, p.ID)
+	}
+	var stored Payout
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored.Version != p.Version {
+		return fmt.Errorf("%w: %s", ErrConflict, p.ID)
+	}
+
+	p.Version++
+	if err := s.put(p); err != nil {
+		p.Version--
+		return err
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List(ctx context.Context, states ...State) ([]*Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var payouts []*Payout
+	for _, data := range s.payouts {
+		var p Payout
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		for _, state := range states {
+			if p.State == state {
+				payouts = append(payouts, &p)
+				break
+			}
+		}
+	}
+	sort.Slice(payouts, func(i, j int) bool { return payouts[i].CreatedAt.Before(payouts[j].CreatedAt) })
+	return payouts, nil
+}
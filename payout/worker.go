@@ -0,0 +1,78 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Worker submits approved payouts and resolves in-flight ones. It starts
+// with a pass over the store, so payouts left approved, submitted or
+// pending by a previous process are resumed after a restart.
+type Worker struct {
+	// Machine drives the payouts. It is required.
+	Machine *Machine
+
+	// Interval is the time between passes. It defaults to one minute.
+	Interval time.Duration
+
+	// Logger, if set, receives the errors of individual payouts,
+	// which do not stop the worker.
+	Logger *slog.Logger
+}
+
+// Run makes a pass every Interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Pass(ctx); err != nil && ctx.Err() == nil && w.Logger != nil {
+			w.Logger.Error("payout worker pass failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass submits every approved payout and refreshes every pending one,
+// and every submitted one older than the machine's ResendAfter, once.
+// It returns the errors of the payouts it could not advance, joined.
+func (w *Worker) Pass(ctx context.Context) error {
+	payouts, err := w.Machine.Store.List(ctx, StateApproved, StateSubmitted, StatePending)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range payouts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		switch {
+		case p.State == StateApproved:
+			_, err = w.Machine.Submit(ctx, p.ID)
+		case p.State == StateSubmitted && !w.Machine.resendable(p):
+			// Possibly still being sent by another worker.
+			continue
+		default:
+			_, err = w.Machine.Refresh(ctx, p.ID)
+		}
+		if err != nil {
+			if w.Logger != nil {
+				w.Logger.Warn("payout not advanced", "id", p.ID, "state", p.State, "error", err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
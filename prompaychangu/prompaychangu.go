@@ -0,0 +1,236 @@
+// Package prompaychangu exposes Prometheus metrics for the PayChangu
+// client. Register a Collector and add its middleware to the client:
+//
+//	collector := prompaychangu.NewCollector()
+//	prometheus.MustRegister(collector)
+//
+//	client := paychangu.New("your_secret_key",
+//	    paychangu.WithMiddleware(collector.Middleware()),
+//	)
+//
+// Only programs that import this package depend on the Prometheus client.
+package prompaychangu
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Outcomes reported in the outcome label of the requests counter.
+const (
+	OutcomeSuccess        = "success"
+	OutcomeClientError    = "client_error"
+	OutcomeServerError    = "server_error"
+	OutcomeRateLimited    = "rate_limited"
+	OutcomeTransportError = "transport_error"
+)
+
+// config holds the settings of a Collector.
+type config struct {
+	namespace string
+	buckets   []float64
+}
+
+// An Option configures a Collector.
+type Option func(*config)
+
+// WithNamespace sets the prefix of all metric names. It defaults to "paychangu".
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the latency histogram buckets, in seconds.
+// It defaults to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// Collector is a prometheus.Collector for PayChangu API calls. It exposes:
+//
+//   - <namespace>_requests_total{operation, outcome}
+//   - <namespace>_request_duration_seconds{operation}
+//   - <namespace>_retries_total{operation}
+//   - <namespace>_rate_limited_total{operation}
+//   - <namespace>_payout_amount_total{currency, operator, status}
+//
+// The payout amount is the amount of each payout initiated, labelled with
+// the mobile money operator or bank that received it and with the outcome
+// of the call as status. The currency and operator are read from the
+// response, so they are empty for payouts that failed.
+type Collector struct {
+	requests    *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	retries     *prometheus.CounterVec
+	rateLimited *prometheus.CounterVec
+	payouts     *prometheus.CounterVec
+}
+
+// NewCollector creates a Collector. It must be registered with
+// a prometheus.Registerer and its Middleware added to the client.
+func NewCollector(opts ...Option) *Collector {
+	c := config{namespace: "paychangu", buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      "requests_total",
+			Help:      "Number of PayChangu API calls by operation and outcome.",
+		}, []string{"operation", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of PayChangu API calls, including retries.",
+			Buckets:   c.buckets,
+		}, []string{"operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      "retries_total",
+			Help:      "Number of retried PayChangu API requests.",
+		}, []string{"operation"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      "rate_limited_total",
+			Help:      "Number of PayChangu API responses with status 429.",
+		}, []string{"operation"}),
+		payouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      "payout_amount_total",
+			Help:      "Amount of payouts initiated, by outcome.",
+		}, []string{"currency", "operator", "status"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.retries.Describe(ch)
+	c.rateLimited.Describe(ch)
+	c.payouts.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.retries.Collect(ch)
+	c.rateLimited.Collect(ch)
+	c.payouts.Collect(ch)
+}
+
+// Middleware returns the paychangu.Middleware that records
+// the collector's metrics for every API call.
+func (c *Collector) Middleware() paychangu.Middleware {
+	return func(next paychangu.Doer) paychangu.Doer {
+		return paychangu.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op, _ := paychangu.OperationFromContext(req.Context())
+			name := op.Name
+			if name == "" {
+				name = req.Method
+			}
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			c.duration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+			stats := paychangu.StatsFromContext(req.Context())
+			if stats.Retries > 0 {
+				c.retries.WithLabelValues(name).Add(float64(stats.Retries))
+			}
+			if stats.RateLimited > 0 {
+				c.rateLimited.WithLabelValues(name).Add(float64(stats.RateLimited))
+			}
+
+			result := outcome(resp, err)
+			c.requests.WithLabelValues(name, result).Inc()
+
+			switch op.Name {
+			case "InitiateMobileMoneyPayout", "InitiateBankPayout":
+				c.observePayout(op, resp, result)
+			}
+
+			return resp, err
+		})
+	}
+}
+
+// outcome classifies the result of an API call.
+func outcome(resp *http.Response, err error) string {
+	switch {
+	case err != nil:
+		return OutcomeTransportError
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return OutcomeRateLimited
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return OutcomeServerError
+	case resp.StatusCode >= http.StatusBadRequest:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// payoutResponse holds the fields of a mobile money or
+// bank payout response that the payout metric needs.
+type payoutResponse struct {
+	Data struct {
+		Transaction struct {
+			Currency    string  `json:"currency"`
+			Amount      float64 `json:"amount"`
+			MobileMoney struct {
+				Name string `json:"name"`
+			} `json:"mobile_money"`
+			RecipientAccountDetails struct {
+				BankName string `json:"bank_name"`
+			} `json:"recipient_account_details"`
+		} `json:"transaction"`
+	} `json:"data"`
+}
+
+// observePayout adds the amount of a payout to the payout metric.
+// Successful responses supply the amount, currency and operator, and
+// their body is restored for the client; otherwise the amount of the
+// operation is used.
+func (c *Collector) observePayout(op paychangu.Operation, resp *http.Response, result string) {
+	var payout payoutResponse
+	if result == OutcomeSuccess {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err == nil {
+			json.Unmarshal(body, &payout)
+		}
+	}
+
+	tx := payout.Data.Transaction
+	amount, currency := tx.Amount, tx.Currency
+	if amount == 0 {
+		amount = op.Amount
+	}
+	if !(amount >= 0) || math.IsInf(amount, 0) {
+		// Counters cannot go down; the API rejects such amounts anyway.
+		amount = 0
+	}
+	if currency == "" {
+		currency = op.Currency
+	}
+	operator := tx.MobileMoney.Name
+	if operator == "" {
+		operator = tx.RecipientAccountDetails.BankName
+	}
+	c.payouts.WithLabelValues(currency, operator, result).Add(amount)
+}
@@ -0,0 +1,48 @@
+package prompaychangu
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/santinalbrowns/paychangu"
+)
+
+func TestPayoutAmountByStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/fail/") {
+			http.Error(w, `{"message":"invalid operator"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"status":"success","data":{"transaction":{"currency":"MWK","amount":1500,"mobile_money":{"name":"Airtel Money"}}}}`)
+	}))
+	defer server.Close()
+
+	collector := NewCollector()
+	client := paychangu.New("SEC-TEST-key",
+		paychangu.WithBaseURL(server.URL),
+		paychangu.WithMiddleware(collector.Middleware()),
+	)
+
+	if _, err := client.InitiateMobileMoneyPayout(paychangu.NewMobileMoneyPayoutRequest("0991234567", "op", 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(collector.payouts.WithLabelValues("MWK", "Airtel Money", OutcomeSuccess)); got != 1500 {
+		t.Errorf("successful payout amount = %v, want 1500", got)
+	}
+
+	client = paychangu.New("SEC-TEST-key",
+		paychangu.WithBaseURL(server.URL+"/fail"),
+		paychangu.WithMiddleware(collector.Middleware()),
+	)
+	if _, err := client.InitiateMobileMoneyPayout(paychangu.NewMobileMoneyPayoutRequest("0991234567", "op", 700)); err == nil {
+		t.Fatal("expected the payout to fail")
+	}
+	if got := testutil.ToFloat64(collector.payouts.WithLabelValues("MWK", "", OutcomeClientError)); got != 700 {
+		t.Errorf("failed payout amount = %v, want 700", got)
+	}
+}
@@ -0,0 +1,226 @@
+package paychangu
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OperationClass groups operations that share a rate limit.
+type OperationClass string
+
+// Operation classes used by RateLimiter.
+const (
+	// ClassCollections covers calls that start a collection,
+	// such as InitiatePayment and CreateVirtualAccount.
+	ClassCollections OperationClass = "collections"
+
+	// ClassPayouts covers InitiateMobileMoneyPayout and InitiateBankPayout.
+	ClassPayouts OperationClass = "payouts"
+
+	// ClassLookups covers every other call, such as
+	// verifications, payout details and operator lists.
+	ClassLookups OperationClass = "lookups"
+)
+
+// Class returns the operation class that op is rate limited under.
+func (op Operation) Class() OperationClass {
+	switch op.Name {
+	case "InitiatePayment", "CreateVirtualAccount":
+		return ClassCollections
+	case "InitiateMobileMoneyPayout", "InitiateBankPayout":
+		return ClassPayouts
+	default:
+		return ClassLookups
+	}
+}
+
+// Limit is the token bucket configuration for an operation class.
+type Limit struct {
+	// Rate is the number of calls allowed per second on average.
+	Rate float64
+
+	// Burst is the number of calls that may be made at once.
+	Burst int
+}
+
+// RateLimiter is a token bucket limiter with one bucket per operation
+// class. It is safe for concurrent use and can be shared by several
+// clients so that they draw from the same budget. Classes without a
+// Limit are not limited.
+//
+// The limiter adapts to the API: a 429 response with a Retry-After
+// header, or X-RateLimit-Remaining reaching zero, pauses the class
+// until the time given by Retry-After or X-RateLimit-Reset.
+//
+// Example Usage:
+//
+//	limiter := paychangu.NewRateLimiter(map[paychangu.OperationClass]paychangu.Limit{
+//	    paychangu.ClassPayouts: {Rate: 5, Burst: 10},
+//	    paychangu.ClassLookups: {Rate: 20, Burst: 20},
+//	})
+//	client := paychangu.New("your_secret_key", paychangu.WithRateLimiter(limiter))
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[OperationClass]*bucket
+}
+
+// bucket holds the state of one operation class.
+type bucket struct {
+	limit       Limit
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with the given limits per class.
+func NewRateLimiter(limits map[OperationClass]Limit) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[OperationClass]*bucket, len(limits))}
+	now := time.Now()
+	for class, limit := range limits {
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		l.buckets[class] = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+	}
+	return l
+}
+
+// WithRateLimiter makes every call wait for limiter before it is sent,
+// including each retry. Calls block until a token is available or
+// their context is done.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(p *payChangu) {
+		p.limiter = limiter
+	}
+}
+
+// Wait blocks until a call of the given class may be made,
+// or returns ctx.Err() if ctx is done first.
+func (l *RateLimiter) Wait(ctx context.Context, class OperationClass) error {
+	for {
+		wait, ok := l.reserve(class)
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token for class if one is available. Otherwise
+// it returns how long to wait before trying again.
+func (l *RateLimiter) reserve(class OperationClass) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[class]
+	if !ok {
+		return 0, true
+	}
+
+	now := time.Now()
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now), false
+	}
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.limit.Rate <= 0 {
+		return time.Second, false
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second)), false
+}
+
+// refill adds the tokens earned since the last refill.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	}
+}
+
+// observe adapts the bucket of class to the rate limit headers of resp.
+func (l *RateLimiter) observe(class OperationClass, resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[class]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		b.refill(now)
+		b.tokens = math.Min(b.tokens, float64(remaining))
+		if remaining <= 0 {
+			if reset, ok := parseReset(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+				b.pause(reset)
+			}
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		b.tokens = 0
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			b.pause(now.Add(time.Duration(secs) * time.Second))
+		} else if reset, ok := parseReset(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+			b.pause(reset)
+		}
+	}
+}
+
+// pause stops the bucket from handing out tokens until t.
+func (b *bucket) pause(t time.Time) {
+	if t.After(b.pausedUntil) {
+		b.pausedUntil = t
+	}
+}
+
+// parseReset parses an X-RateLimit-Reset header, which
+// holds either a Unix time or a number of seconds from now.
+func parseReset(value string, now time.Time) (time.Time, bool) {
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || secs <= 0 {
+		return time.Time{}, false
+	}
+	if secs > 1_000_000_000 {
+		return time.Unix(secs, 0), true
+	}
+	return now.Add(time.Duration(secs) * time.Second), true
+}
+
+// Middleware returns the Middleware that applies the limiter.
+// WithRateLimiter installs it inside the retries; use this
+// directly with WithMiddleware to place it elsewhere.
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op, _ := OperationFromContext(req.Context())
+			class := op.Class()
+			if err := l.Wait(req.Context(), class); err != nil {
+				return nil, err
+			}
+
+			resp, err := next.Do(req)
+			if err == nil {
+				l.observe(class, resp)
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// csvHeader is the header row written by WriteCSV.
+var csvHeader = []string{
+	"issue", "kind", "ref",
+	"local_amount", "local_charges", "local_currency", "local_status",
+	"remote_amount", "remote_charges", "remote_currency", "remote_status",
+	"time", "detail",
+}
+
+// WriteCSV writes one row per discrepancy, with a header row.
+// Matched transactions are not included.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, d := range r.Discrepancies {
+		row := []string{string(d.Issue), string(d.Kind), d.Ref}
+		row = append(row, csvFields(d.Local)...)
+		row = append(row, csvFields(d.Remote)...)
+
+		var t time.Time
+		if d.Local != nil {
+			t = d.Local.Time
+		} else if d.Remote != nil {
+			t = d.Remote.Time
+		}
+		timestamp := ""
+		if !t.IsZero() {
+			timestamp = t.Format(time.RFC3339)
+		}
+		cw.Write(append(row, timestamp, d.Detail))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFields returns the amount, charges, currency and status
+// columns of t, or empty columns if t is nil.
+func csvFields(t *Transaction) []string {
+	if t == nil {
+		return []string{"", "", "", ""}
+	}
+	return []string{
+		strconv.FormatFloat(t.Amount, 'f', 2, 64),
+		strconv.FormatFloat(t.Charges, 'f', 2, 64),
+		t.Currency,
+		t.Status,
+	}
+}
@@ -0,0 +1,343 @@
+// Package reconcile compares internal ledger entries with the
+// collections and payouts PayChangu has on record.
+//
+// Each ledger entry in a date window is looked up at PayChangu by its
+// TxRef or ChargeID, and the two sides are compared. The API has no
+// endpoint listing every transaction in a window, so transactions that
+// PayChangu has but the ledger lacks are only found when a Source of
+// PayChangu-side records, such as stored webhooks, is given.
+//
+// Example Usage:
+//
+//	r := reconcile.Reconciler{Client: client, Ledger: ledger, Source: webhookLog}
+//	report, err := r.Run(ctx, start, start.AddDate(0, 0, 1))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	report.WriteCSV(os.Stdout)
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Kind is the kind of a transaction, which decides how it is looked up.
+type Kind string
+
+// Transaction kinds.
+const (
+	KindCollection        Kind = "collection"
+	KindMobileMoneyPayout Kind = "mobile_money_payout"
+	KindBankPayout        Kind = "bank_payout"
+)
+
+// Transaction is a collection or payout as recorded on one side.
+type Transaction struct {
+	Kind Kind `json:"kind"`
+
+	// Ref is the TxRef of a collection or the ChargeID of a payout.
+	Ref string `json:"ref"`
+
+	Amount   float64 `json:"amount"`
+	Charges  float64 `json:"charges"`
+	Currency string  `json:"currency"`
+	Status   string  `json:"status"`
+
+	// Time is when the transaction was created.
+	Time time.Time `json:"time"`
+}
+
+// Ledger gives access to internal ledger entries.
+type Ledger interface {
+	// Entries returns the entries created in [from, to).
+	Entries(ctx context.Context, from, to time.Time) ([]Transaction, error)
+}
+
+// Source gives access to PayChangu-side records collected
+// independently of the ledger, for example from webhooks.
+type Source interface {
+	// Transactions returns the transactions created in [from, to).
+	Transactions(ctx context.Context, from, to time.Time) ([]Transaction, error)
+}
+
+// Client is the part of the PayChangu client used to look transactions up.
+type Client interface {
+	VerifyPaymentContext(ctx context.Context, txRef string) (*paychangu.VerifyPaymentResponse, error)
+	GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error)
+	GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error)
+}
+
+// Issue is the kind of a discrepancy.
+type Issue string
+
+// Discrepancy issues.
+const (
+	// MissingLocally means PayChangu has the transaction but the ledger does not.
+	MissingLocally Issue = "missing_on_our_side"
+
+	// MissingAtPayChangu means the ledger has the transaction but PayChangu does not.
+	MissingAtPayChangu Issue = "missing_at_paychangu"
+
+	// AmountMismatch means the amount, charges or currency differ.
+	AmountMismatch Issue = "amount_mismatch"
+
+	// StatusMismatch means the statuses differ after NormalizeStatus.
+	StatusMismatch Issue = "status_mismatch"
+
+	// LookupFailed means the transaction could not be looked up at
+	// PayChangu, so it was not compared. Run again to retry it.
+	LookupFailed Issue = "lookup_failed"
+)
+
+// Discrepancy is a difference between the ledger and PayChangu.
+type Discrepancy struct {
+	Issue Issue  `json:"issue"`
+	Kind  Kind   `json:"kind"`
+	Ref   string `json:"ref"`
+
+	// Local is the ledger entry, if any.
+	Local *Transaction `json:"local,omitempty"`
+
+	// Remote is the PayChangu record, if any.
+	Remote *Transaction `json:"remote,omitempty"`
+
+	// Detail describes the difference.
+	Detail string `json:"detail"`
+}
+
+// Report is the result of a reconciliation run.
+type Report struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Matched is the number of transactions that agree on both sides.
+	Matched int `json:"matched"`
+
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Reconciler compares a Ledger with PayChangu.
+type Reconciler struct {
+	// Client looks ledger entries up at PayChangu. It is required.
+	Client Client
+
+	// Ledger provides the internal entries. It is required.
+	Ledger Ledger
+
+	// Source, if set, provides PayChangu-side records
+	// used to find transactions missing from the ledger.
+	Source Source
+
+	// Concurrency is the number of lookups made at once. It defaults to 4.
+	Concurrency int
+}
+
+// Run reconciles the transactions created in [from, to). Entries that
+// fail to be looked up are reported as LookupFailed rather than failing
+// the run, but if ctx is done before every entry was looked up, Run
+// returns its error.
+func (r *Reconciler) Run(ctx context.Context, from, to time.Time) (*Report, error) {
+	entries, err := r.Ledger.Entries(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	remotes, errs := r.lookupAll(ctx, entries)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{From: from, To: to, Discrepancies: []Discrepancy{}}
+	seen := make(map[string]bool, len(entries))
+	for i := range entries {
+		local := &entries[i]
+		seen[key(local.Kind, local.Ref)] = true
+
+		if errs[i] != nil {
+			report.add(Discrepancy{Issue: LookupFailed, Kind: local.Kind, Ref: local.Ref, Local: local,
+				Detail: errs[i].Error()})
+			continue
+		}
+		if remotes[i] == nil {
+			report.add(Discrepancy{Issue: MissingAtPayChangu, Kind: local.Kind, Ref: local.Ref, Local: local,
+				Detail: "not found at PayChangu"})
+			continue
+		}
+		if d, ok := compare(local, remotes[i]); ok {
+			report.add(d)
+			continue
+		}
+		report.Matched++
+	}
+
+	if r.Source != nil {
+		records, err := r.Source.Transactions(ctx, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source: %w", err)
+		}
+		for i := range records {
+			remote := &records[i]
+			if seen[key(remote.Kind, remote.Ref)] {
+				continue
+			}
+			seen[key(remote.Kind, remote.Ref)] = true
+			report.add(Discrepancy{Issue: MissingLocally, Kind: remote.Kind, Ref: remote.Ref, Remote: remote,
+				Detail: "not found in ledger"})
+		}
+	}
+
+	sort.SliceStable(report.Discrepancies, func(i, j int) bool {
+		a, b := report.Discrepancies[i], report.Discrepancies[j]
+		if a.Issue != b.Issue {
+			return a.Issue < b.Issue
+		}
+		return a.Ref < b.Ref
+	})
+	return report, nil
+}
+
+// add records a discrepancy.
+func (r *Report) add(d Discrepancy) {
+	r.Discrepancies = append(r.Discrepancies, d)
+}
+
+// key identifies a transaction across both sides.
+func key(kind Kind, ref string) string {
+	return string(kind) + ":" + ref
+}
+
+// lookupAll looks every entry up at PayChangu, returning the record and
+// the lookup error of each. Records are nil for entries PayChangu has no
+// record of. No lookups are started once ctx is done.
+func (r *Reconciler) lookupAll(ctx context.Context, entries []Transaction) ([]*Transaction, []error) {
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	remotes := make([]*Transaction, len(entries))
+	errs := make([]error, len(entries))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range entries {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			remotes[i], errs[i] = r.lookup(ctx, entries[i])
+		}(i)
+	}
+	wg.Wait()
+	return remotes, errs
+}
+
+// lookup fetches the PayChangu record of entry,
+// returning nil if PayChangu has none.
+func (r *Reconciler) lookup(ctx context.Context, entry Transaction) (*Transaction, error) {
+	var remote *Transaction
+	var err error
+
+	switch entry.Kind {
+	case KindCollection:
+		var resp *paychangu.VerifyPaymentResponse
+		if resp, err = r.Client.VerifyPaymentContext(ctx, entry.Ref); err == nil {
+			d := resp.Data
+			remote = &Transaction{Amount: d.Amount, Charges: d.Charges, Currency: d.Currency, Status: d.Status, Time: d.CreatedAt}
+		}
+	case KindMobileMoneyPayout:
+		var d *paychangu.PayoutTransactionDetails
+		if d, err = r.Client.GetMobileMoneyPayoutDetailsContext(ctx, entry.Ref); err == nil {
+			remote = &Transaction{Amount: d.Amount, Charges: charges(d.TransactionCharges), Currency: d.Currency, Status: d.Status, Time: d.CreatedAt}
+		}
+	case KindBankPayout:
+		var d *paychangu.BankPayoutTransactionDetails
+		if d, err = r.Client.GetBankPayoutDetailsContext(ctx, entry.Ref); err == nil {
+			remote = &Transaction{Amount: d.Amount, Charges: charges(d.TransactionCharges), Currency: d.Currency, Status: d.Status, Time: d.CreatedAt}
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown kind %q", entry.Ref, entry.Kind)
+	}
+
+	var apiErr *paychangu.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s %s: %w", entry.Kind, entry.Ref, err)
+	}
+
+	remote.Kind = entry.Kind
+	remote.Ref = entry.Ref
+	return remote, nil
+}
+
+// charges parses the fee of a payout, which the API returns as a string.
+func charges(c paychangu.TransactionCharges) float64 {
+	amount, _ := strconv.ParseFloat(c.Amount, 64)
+	return amount
+}
+
+// compare returns the discrepancy between local and remote, if any.
+// Amounts and charges are compared in minor units, so that floating
+// point noise below a hundredth does not count as a mismatch.
+func compare(local, remote *Transaction) (Discrepancy, bool) {
+	d := Discrepancy{Kind: local.Kind, Ref: local.Ref, Local: local, Remote: remote}
+
+	var diffs []string
+	if !strings.EqualFold(local.Currency, remote.Currency) {
+		diffs = append(diffs, fmt.Sprintf("currency %s != %s", local.Currency, remote.Currency))
+	}
+	if minor(local.Amount) != minor(remote.Amount) {
+		diffs = append(diffs, fmt.Sprintf("amount %.2f != %.2f", local.Amount, remote.Amount))
+	}
+	if minor(local.Charges) != minor(remote.Charges) {
+		diffs = append(diffs, fmt.Sprintf("charges %.2f != %.2f", local.Charges, remote.Charges))
+	}
+	if len(diffs) > 0 {
+		d.Issue = AmountMismatch
+		d.Detail = strings.Join(diffs, "; ")
+		return d, true
+	}
+
+	if NormalizeStatus(local.Status) != NormalizeStatus(remote.Status) {
+		d.Issue = StatusMismatch
+		d.Detail = fmt.Sprintf("status %s != %s", local.Status, remote.Status)
+		return d, true
+	}
+
+	return Discrepancy{}, false
+}
+
+// minor converts an amount to minor units.
+func minor(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// NormalizeStatus maps the different spellings of a transaction
+// status to "success", "failed" or "pending" with
+// paychangu.NormalizeStatus. A reversed transaction counts as failed,
+// since its money was returned. Other statuses are returned in lower case.
+func NormalizeStatus(status string) string {
+	if s := paychangu.NormalizeStatus(status); s != paychangu.StatusReversed {
+		return s
+	}
+	return paychangu.StatusFailed
+}
@@ -0,0 +1,112 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// ledger is a Ledger holding fixed entries.
+type ledger []Transaction
+
+func (l ledger) Entries(ctx context.Context, from, to time.Time) ([]Transaction, error) {
+	return l, nil
+}
+
+// payouts is a Client that knows mobile money payouts by ChargeID. A
+// lookup of a ChargeID in errs fails with its error.
+type payouts struct {
+	known   map[string]paychangu.PayoutTransactionDetails
+	errs    map[string]error
+	lookups atomic.Int32
+}
+
+func (c *payouts) VerifyPaymentContext(ctx context.Context, txRef string) (*paychangu.VerifyPaymentResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *payouts) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error) {
+	c.lookups.Add(1)
+	if err := c.errs[chargeID]; err != nil {
+		return nil, err
+	}
+	d, ok := c.known[chargeID]
+	if !ok {
+		return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+	}
+	return &d, nil
+}
+
+func (c *payouts) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error) {
+	return nil, errors.New("not implemented")
+}
+
+func payout(ref string) Transaction {
+	return Transaction{Kind: KindMobileMoneyPayout, Ref: ref, Amount: 1000, Currency: "MWK", Status: "success"}
+}
+
+func TestRunReportsFailedLookups(t *testing.T) {
+	client := &payouts{
+		known: map[string]paychangu.PayoutTransactionDetails{
+			"PAYOUT-1": {ChargeID: "PAYOUT-1", Amount: 1000, Currency: "MWK", Status: "successful"},
+		},
+		errs: map[string]error{"PAYOUT-2": &paychangu.APIError{StatusCode: http.StatusServiceUnavailable}},
+	}
+	r := Reconciler{Client: client, Ledger: ledger{payout("PAYOUT-1"), payout("PAYOUT-2"), payout("PAYOUT-3")}}
+
+	report, err := r.Run(context.Background(), time.Time{}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Matched != 1 || len(report.Discrepancies) != 2 {
+		t.Fatalf("report = %+v, want 1 match and 2 discrepancies", report)
+	}
+	if d := report.Discrepancies[0]; d.Issue != LookupFailed || d.Ref != "PAYOUT-2" || d.Detail == "" {
+		t.Errorf("discrepancy = %+v, want PAYOUT-2 lookup_failed", d)
+	}
+	if d := report.Discrepancies[1]; d.Issue != MissingAtPayChangu || d.Ref != "PAYOUT-3" {
+		t.Errorf("discrepancy = %+v, want PAYOUT-3 missing_at_paychangu", d)
+	}
+}
+
+func TestRunStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := &payouts{}
+	r := Reconciler{Client: client, Ledger: ledger{payout("PAYOUT-1"), payout("PAYOUT-2")}, Concurrency: 1}
+	if _, err := r.Run(ctx, time.Time{}, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if n := client.lookups.Load(); n != 0 {
+		t.Errorf("made %d lookups after cancellation", n)
+	}
+}
+
+func TestMatchSettlementsMixedCurrencies(t *testing.T) {
+	payments := []paychangu.PaymentDetails{
+		{TxRef: "TX-1", Amount: 1000, Charges: 30, Currency: "MWK", Status: "success"},
+		{TxRef: "TX-2", Amount: 10, Charges: 1, Currency: "USD", Status: "success"},
+	}
+	records := []SettlementRecord{
+		{BatchID: "BATCH-1", TxRef: "TX-1", Currency: "MWK", Amount: 1000, Charges: 30, NetAmount: 970},
+		{BatchID: "BATCH-1", TxRef: "TX-2", Currency: "USD", Amount: 10, Charges: 1, NetAmount: 9},
+	}
+
+	batches := MatchSettlements(records, payments, nil)
+	if len(batches) != 1 {
+		t.Fatalf("got %d batches, want 1", len(batches))
+	}
+	b := batches[0]
+	if b.Currency != "MWK" || b.Settled != 970 || b.Expected != 970 || b.Balanced {
+		t.Errorf("batch = %+v, want MWK sums only and unbalanced", b)
+	}
+	if len(b.Issues) != 1 || b.Issues[0].TxRef != "TX-2" {
+		t.Errorf("issues = %+v, want one for TX-2", b.Issues)
+	}
+}
@@ -0,0 +1,296 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// SettlementRecord is one line of a PayChangu settlement report:
+// a collected payment paid out to the merchant in a settlement batch.
+type SettlementRecord struct {
+	BatchID   string    `json:"batch_id"`
+	SettledAt time.Time `json:"settled_at"`
+	TxRef     string    `json:"tx_ref"`
+	Reference string    `json:"reference"`
+	Currency  string    `json:"currency"`
+
+	// Amount is the amount collected from the customer.
+	Amount float64 `json:"amount"`
+
+	// Charges is the fee PayChangu deducted.
+	Charges float64 `json:"charges"`
+
+	// NetAmount is the amount paid to the merchant.
+	NetAmount float64 `json:"net_amount"`
+}
+
+// settlementColumns maps the field names of SettlementRecord to the
+// column headers they may appear under in a report, in lower case.
+var settlementColumns = map[string][]string{
+	"batch_id":   {"batch_id", "batch", "settlement_id", "settlement_batch", "payout_id"},
+	"settled_at": {"settled_at", "settlement_date", "date", "payout_date"},
+	"tx_ref":     {"tx_ref", "txref", "transaction_ref"},
+	"reference":  {"reference", "ref", "transaction_reference"},
+	"currency":   {"currency"},
+	"amount":     {"amount", "gross_amount", "transaction_amount"},
+	"charges":    {"charges", "charge", "fee", "fees"},
+	"net_amount": {"net_amount", "net", "settled_amount", "amount_settled"},
+}
+
+// settlementTimeLayouts are the date formats accepted for settled_at.
+var settlementTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02", "02/01/2006"}
+
+// ParseSettlementCSV parses a settlement report exported as CSV. Columns
+// are found by their header, ignoring case, spaces and common aliases
+// such as "fee" for charges. A batch ID, an amount and a TxRef or
+// reference are required. A missing net amount is taken to be the
+// amount less charges.
+func ParseSettlementCSV(r io.Reader) ([]SettlementRecord, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settlement header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+		for field, aliases := range settlementColumns {
+			for _, alias := range aliases {
+				if _, ok := columns[field]; !ok && name == alias {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["batch_id"]; !ok {
+		return nil, errors.New("settlement report has no batch ID column")
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, errors.New("settlement report has no amount column")
+	}
+	_, hasTxRef := columns["tx_ref"]
+	_, hasReference := columns["reference"]
+	if !hasTxRef && !hasReference {
+		return nil, errors.New("settlement report has no tx_ref or reference column")
+	}
+
+	var records []SettlementRecord
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("settlement report line %d: %w", line, err)
+		}
+
+		record, err := parseSettlementRow(row, columns)
+		if err != nil {
+			return nil, fmt.Errorf("settlement report line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// parseSettlementRow converts one CSV row into a SettlementRecord.
+func parseSettlementRow(row []string, columns map[string]int) (SettlementRecord, error) {
+	get := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	number := func(field string) (float64, error) {
+		value := strings.ReplaceAll(get(field), ",", "")
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", field, get(field))
+		}
+		return n, nil
+	}
+
+	record := SettlementRecord{
+		BatchID:   get("batch_id"),
+		TxRef:     get("tx_ref"),
+		Reference: get("reference"),
+		Currency:  get("currency"),
+	}
+	if record.BatchID == "" {
+		return SettlementRecord{}, errors.New("missing batch ID")
+	}
+	if record.TxRef == "" && record.Reference == "" {
+		return SettlementRecord{}, errors.New("missing tx_ref and reference")
+	}
+
+	var err error
+	if record.Amount, err = number("amount"); err != nil {
+		return SettlementRecord{}, err
+	}
+	if record.Charges, err = number("charges"); err != nil {
+		return SettlementRecord{}, err
+	}
+	if get("net_amount") == "" {
+		record.NetAmount = record.Amount - record.Charges
+	} else if record.NetAmount, err = number("net_amount"); err != nil {
+		return SettlementRecord{}, err
+	}
+
+	if value := get("settled_at"); value != "" {
+		for _, layout := range settlementTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				record.SettledAt = t
+				break
+			}
+		}
+		if record.SettledAt.IsZero() {
+			return SettlementRecord{}, fmt.Errorf("invalid settled_at %q", value)
+		}
+	}
+
+	return record, nil
+}
+
+// SettlementIssue is a settlement record that does
+// not agree with the verified payment it settles.
+type SettlementIssue struct {
+	TxRef     string `json:"tx_ref"`
+	Reference string `json:"reference"`
+	Detail    string `json:"detail"`
+}
+
+// BatchReport proves, or disproves, one settlement batch: that what
+// was collected minus fees equals what was settled and deposited.
+type BatchReport struct {
+	BatchID  string `json:"batch_id"`
+	Currency string `json:"currency"`
+
+	// Records is the number of records in the batch.
+	Records int `json:"records"`
+
+	// Collected and Charges are summed from the verified payments
+	// of the records in Currency.
+	Collected float64 `json:"collected"`
+	Charges   float64 `json:"charges"`
+
+	// Expected is Collected less Charges.
+	Expected float64 `json:"expected"`
+
+	// Settled is the sum of the batch's net amounts in the report.
+	Settled float64 `json:"settled"`
+
+	// Deposited is the amount that landed in the bank, if known.
+	Deposited *float64 `json:"deposited,omitempty"`
+
+	// Balanced is true when the batch has no issues and Expected
+	// equals Settled and, if known, Deposited.
+	Balanced bool `json:"balanced"`
+
+	Issues []SettlementIssue `json:"issues"`
+}
+
+// MatchSettlements matches settlement records against verified payments,
+// found by TxRef or else by Reference, and summarizes each batch.
+// Amounts and charges are compared in minor units. deposits optionally
+// maps batch IDs to the amount that landed in the bank for them; it may
+// be nil. Batches are returned sorted by ID.
+//
+// A batch is in the currency of its first record that has one. Records
+// in another currency are reported as issues and left out of its sums,
+// which would otherwise add up amounts in different currencies.
+func MatchSettlements(records []SettlementRecord, payments []paychangu.PaymentDetails, deposits map[string]float64) []BatchReport {
+	byTxRef := make(map[string]*paychangu.PaymentDetails, len(payments))
+	byReference := make(map[string]*paychangu.PaymentDetails, len(payments))
+	for i := range payments {
+		p := &payments[i]
+		if p.TxRef != "" {
+			byTxRef[p.TxRef] = p
+		}
+		if p.Reference != "" {
+			byReference[p.Reference] = p
+		}
+	}
+
+	batches := make(map[string]*BatchReport)
+	var ids []string
+	for _, record := range records {
+		batch, ok := batches[record.BatchID]
+		if !ok {
+			batch = &BatchReport{BatchID: record.BatchID, Currency: record.Currency, Issues: []SettlementIssue{}}
+			batches[record.BatchID] = batch
+			ids = append(ids, record.BatchID)
+		}
+		batch.Records++
+
+		issue := func(format string, args ...any) {
+			batch.Issues = append(batch.Issues, SettlementIssue{
+				TxRef:     record.TxRef,
+				Reference: record.Reference,
+				Detail:    fmt.Sprintf(format, args...),
+			})
+		}
+
+		if batch.Currency == "" {
+			batch.Currency = record.Currency
+		}
+		if record.Currency != "" && !strings.EqualFold(record.Currency, batch.Currency) {
+			issue("currency %s differs from the batch's %s", record.Currency, batch.Currency)
+			continue
+		}
+		batch.Settled += record.NetAmount
+
+		payment := byTxRef[record.TxRef]
+		if payment == nil {
+			payment = byReference[record.Reference]
+		}
+		if payment == nil {
+			issue("no verified payment")
+			continue
+		}
+
+		batch.Collected += payment.Amount
+		batch.Charges += payment.Charges
+
+		if NormalizeStatus(payment.Status) != "success" {
+			issue("payment status is %s", payment.Status)
+		}
+		if !strings.EqualFold(payment.Currency, record.Currency) && record.Currency != "" {
+			issue("currency %s != %s", record.Currency, payment.Currency)
+		}
+		if minor(payment.Amount) != minor(record.Amount) {
+			issue("amount %.2f != %.2f", record.Amount, payment.Amount)
+		}
+		if minor(payment.Charges) != minor(record.Charges) {
+			issue("charges %.2f != %.2f", record.Charges, payment.Charges)
+		}
+		if minor(record.Amount-record.Charges) != minor(record.NetAmount) {
+			issue("net amount %.2f != amount less charges %.2f", record.NetAmount, record.Amount-record.Charges)
+		}
+	}
+
+	sort.Strings(ids)
+	reports := make([]BatchReport, 0, len(ids))
+	for _, id := range ids {
+		batch := batches[id]
+		batch.Expected = batch.Collected - batch.Charges
+		batch.Balanced = len(batch.Issues) == 0 && minor(batch.Expected) == minor(batch.Settled)
+		if deposited, ok := deposits[id]; ok {
+			batch.Deposited = &deposited
+			batch.Balanced = batch.Balanced && minor(deposited) == minor(batch.Settled)
+		}
+		reports = append(reports, *batch)
+	}
+	return reports
+}
@@ -0,0 +1,189 @@
+package paychangu
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidReference is returned by ParseReference for
+// references that were not made by a ReferenceGenerator.
+var ErrInvalidReference = errors.New("paychangu: invalid reference")
+
+// ReferenceGenerator makes collision-resistant TxRef and ChargeID values.
+// Each reference ends in a ULID: a 48-bit millisecond timestamp followed by
+// 80 random bits, encoded as 26 Crockford base32 characters. References
+// sort by creation time, and references made within the same millisecond
+// by one process keep their order.
+//
+// A reference has the form
+//
+//	[Prefix-][Tenant.Type-]ULID
+//
+// Tenant and Type are optional tags that ParseReference can recover,
+// e.g. to route a webhook to the right tenant. They may only contain
+// letters, digits and underscores; other characters are dropped.
+// The zero value generates bare ULIDs and is ready to use.
+//
+// Example Usage:
+//
+//	refs := paychangu.ReferenceGenerator{Prefix: "PAYOUT", Tenant: "acme", Type: "refund"}
+//	chargeID := refs.New() // PAYOUT-acme.refund-01J9Z3K6W8Q4N2R7T5V0XYZABC
+//
+//	ref, err := paychangu.ParseReference(chargeID)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(ref.Tenant, ref.Type, ref.Time)
+type ReferenceGenerator struct {
+	// Prefix starts every reference, e.g. "TX" or "PAYOUT".
+	// It must not contain a dot.
+	Prefix string
+
+	// Tenant, if set, is embedded in every reference.
+	Tenant string
+
+	// Type, if set, is embedded in every reference.
+	Type string
+}
+
+// Reference is a reference taken apart by ParseReference.
+type Reference struct {
+	Prefix string
+	Tenant string
+	Type   string
+
+	// ID is the ULID at the end of the reference.
+	ID string
+
+	// Time is when the reference was generated, to the millisecond.
+	Time time.Time
+}
+
+// NewReference returns a new reference with the given prefix.
+// It is shorthand for ReferenceGenerator{Prefix: prefix}.New().
+func NewReference(prefix string) string {
+	return ReferenceGenerator{Prefix: prefix}.New()
+}
+
+// New returns a new reference.
+func (g ReferenceGenerator) New() string {
+	var b strings.Builder
+	if g.Prefix != "" {
+		b.WriteString(strings.ReplaceAll(g.Prefix, ".", ""))
+		b.WriteByte('-')
+	}
+	tenant, typ := sanitizeTag(g.Tenant), sanitizeTag(g.Type)
+	if tenant != "" || typ != "" {
+		b.WriteString(tenant)
+		b.WriteByte('.')
+		b.WriteString(typ)
+		b.WriteByte('-')
+	}
+	b.WriteString(newULID(time.Now()))
+	return b.String()
+}
+
+// ParseReference takes apart a reference made by a ReferenceGenerator.
+func ParseReference(ref string) (Reference, error) {
+	parts := strings.Split(ref, "-")
+	id := parts[len(parts)-1]
+	t, ok := ulidTime(id)
+	if !ok {
+		return Reference{}, fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+
+	r := Reference{ID: id, Time: t}
+	parts = parts[:len(parts)-1]
+	if n := len(parts); n > 0 && strings.Contains(parts[n-1], ".") {
+		r.Tenant, r.Type, _ = strings.Cut(parts[n-1], ".")
+		parts = parts[:n-1]
+	}
+	r.Prefix = strings.Join(parts, "-")
+	return r, nil
+}
+
+// sanitizeTag drops the characters that may not appear in a tag.
+func sanitizeTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, tag)
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidState keeps ULIDs generated by this process monotonic.
+var ulidState struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// newULID returns a ULID for now. Within the same millisecond, or if
+// the clock goes backwards, the previous random part is incremented
+// instead so that ULIDs keep sorting in the order they were made.
+func newULID(now time.Time) string {
+	ms := uint64(now.UnixMilli())
+
+	ulidState.Lock()
+	if ms <= ulidState.ms {
+		ms = ulidState.ms
+		for i := len(ulidState.entropy) - 1; i >= 0; i-- {
+			ulidState.entropy[i]++
+			if ulidState.entropy[i] != 0 {
+				break
+			}
+		}
+	} else {
+		if _, err := rand.Read(ulidState.entropy[:]); err != nil {
+			panic("paychangu: failed to read random bytes: " + err.Error())
+		}
+		ulidState.ms = ms
+	}
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(id[6:], ulidState.entropy[:])
+	ulidState.Unlock()
+
+	// 26 characters hold 130 bits; the first two are always zero.
+	var out [26]byte
+	for i := range out {
+		v := 0
+		for j := 0; j < 5; j++ {
+			v <<= 1
+			if bit := i*5 + j - 2; bit >= 0 && id[bit/8]&(0x80>>(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out[:])
+}
+
+// ulidTime returns the time encoded in id, if id is a valid ULID.
+func ulidTime(id string) (time.Time, bool) {
+	if len(id) != 26 || id[0] > '7' {
+		return time.Time{}, false
+	}
+
+	var ms uint64
+	for i := 0; i < len(id); i++ {
+		v := strings.IndexByte(crockford, id[i])
+		if v < 0 {
+			return time.Time{}, false
+		}
+		if i < 10 {
+			ms = ms<<5 | uint64(v)
+		}
+	}
+	return time.UnixMilli(int64(ms)), true
+}
@@ -0,0 +1,243 @@
+package paychangu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownMerchant is returned, wrapped, when a Registry
+// has no client for the requested merchant.
+var ErrUnknownMerchant = errors.New("paychangu: unknown merchant")
+
+// Registry holds the clients of many merchants, keyed by merchant ID,
+// for platforms that process payments on their behalf. All clients
+// share one HTTP transport and the options given to NewRegistry, so a
+// RateLimiter or CircuitBreaker passed there is one budget for all
+// merchants. An IdempotencyStore passed there is shared too, with keys
+// prefixed by the merchant ID. It is safe for concurrent use.
+//
+// A Registry is also an http.Handler that receives the webhooks of
+// every merchant; see ServeHTTP.
+//
+// Example Usage:
+//
+//	limiter := paychangu.NewRateLimiter(limits)
+//	registry := paychangu.NewRegistry(paychangu.WithRateLimiter(limiter))
+//	registry.Register("acme", paychangu.Config{SecretKey: acmeKey, WebhookSecret: acmeSecret}, handleAcme)
+//
+//	client, err := registry.Client("acme")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	resp, err := client.VerifyPayment(txRef)
+//
+//	http.Handle("/webhooks/paychangu", registry)
+type Registry struct {
+	opts []Option
+
+	mu        sync.RWMutex
+	merchants map[string]*merchant
+}
+
+// merchant is the registered state of one merchant.
+type merchant struct {
+	client  *payChangu
+	config  Config
+	handler WebhookHandler
+
+	// previousWebhookSecret is still accepted after a rotation,
+	// for webhooks already signed with it.
+	previousWebhookSecret string
+}
+
+// NewRegistry creates an empty Registry. opts are applied to
+// every client the registry creates. Unless opts include
+// WithHTTPClient, the clients share one http.Client.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{
+		opts:      append([]Option{WithHTTPClient(&http.Client{})}, opts...),
+		merchants: make(map[string]*merchant),
+	}
+}
+
+// newClient creates a client for a merchant's config with the registry's
+// options. Config.Timeout is ignored so that clients keep the shared
+// transport. Idempotency keys are scoped to the merchant, so that an
+// IdempotencyStore passed to NewRegistry never replays the result of
+// one merchant's payout for another using the same ChargeID.
+func (r *Registry) newClient(merchantID string, config Config) (*payChangu, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	opts := append([]Option(nil), r.opts...)
+	opts = append(opts, func(p *payChangu) { p.idempotencyScope = merchantID })
+	if config.BaseURL != "" {
+		opts = append(opts, WithBaseURL(config.BaseURL))
+	}
+	if config.MaxRetries > 0 {
+		opts = append(opts, WithRetries(config.MaxRetries))
+	}
+	return New(config.SecretKey, opts...), nil
+}
+
+// Register adds the merchant with the given ID, or replaces it if it is
+// already registered. handler receives the merchant's webhooks; it may
+// be nil if the merchant's webhooks are not received by the registry.
+func (r *Registry) Register(merchantID string, config Config, handler WebhookHandler) error {
+	client, err := r.newClient(merchantID, config)
+	if err != nil {
+		return fmt.Errorf("merchant %s: %w", merchantID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.merchants[merchantID] = &merchant{client: client, config: config, handler: handler}
+	return nil
+}
+
+// Rotate replaces the secret key and webhook secret of a registered
+// merchant without a restart. Calls already in flight finish with the
+// old key. Webhooks signed with the previous webhook secret are still
+// accepted until the next rotation.
+func (r *Registry) Rotate(merchantID, secretKey, webhookSecret string) error {
+	// The write lock is held throughout, so that a concurrent Remove is
+	// not undone and concurrent rotations each keep the secret they
+	// replace.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.merchants[merchantID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownMerchant, merchantID)
+	}
+
+	config := m.config
+	config.SecretKey = secretKey
+	config.WebhookSecret = webhookSecret
+	client, err := r.newClient(merchantID, config)
+	if err != nil {
+		return fmt.Errorf("merchant %s: %w", merchantID, err)
+	}
+
+	r.merchants[merchantID] = &merchant{
+		client:                client,
+		config:                config,
+		handler:               m.handler,
+		previousWebhookSecret: m.config.WebhookSecret,
+	}
+	return nil
+}
+
+// Remove unregisters a merchant.
+func (r *Registry) Remove(merchantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.merchants, merchantID)
+}
+
+// Client returns the client of a registered merchant. Callers should
+// look the client up for each operation rather than keep it, so that
+// they pick up rotated keys.
+func (r *Registry) Client(merchantID string) (*payChangu, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.merchants[merchantID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMerchant, merchantID)
+	}
+	return m.client, nil
+}
+
+// Merchants returns the IDs of the registered merchants in sorted order.
+func (r *Registry) Merchants() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.merchants))
+	for id := range r.merchants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ServeHTTP receives a webhook and passes it to the handler of the
+// merchant it belongs to. The merchant is the one named by the
+// "merchant" query parameter, if given, otherwise the Tenant tag of the
+// event's TxRef or ChargeID (see ReferenceGenerator), otherwise the one
+// whose webhook secret matches the signature. Webhooks are answered
+// with 404 Not Found when no merchant matches and 401 Unauthorized
+// when the signature is invalid.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, err := readWebhook(w, req)
+	if err != nil {
+		return
+	}
+	event, err := decodeWebhook(payload)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	signature := req.Header.Get(SignatureHeader)
+
+	m, err := r.webhookMerchant(req, event, payload, signature)
+	if err != nil {
+		if errors.Is(err, ErrUnknownMerchant) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeWebhookError(w, err)
+		return
+	}
+	if m.handler == nil {
+		http.Error(w, "no webhook handler for merchant", http.StatusNotFound)
+		return
+	}
+
+	serveWebhook(w, req, event, m.handler)
+}
+
+// webhookMerchant finds the merchant a webhook belongs to
+// and checks that the webhook is signed with its secret.
+func (r *Registry) webhookMerchant(req *http.Request, event *WebhookEvent, payload []byte, signature string) (*merchant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id := req.URL.Query().Get("merchant")
+	if id == "" {
+		if ref, err := ParseReference(event.Ref()); err == nil {
+			id = ref.Tenant
+		}
+	}
+
+	if id != "" {
+		m, ok := r.merchants[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMerchant, id)
+		}
+		if !m.verify(payload, signature) {
+			return nil, ErrInvalidSignature
+		}
+		return m, nil
+	}
+
+	for _, m := range r.merchants {
+		if m.verify(payload, signature) {
+			return m, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
+
+// verify reports whether signature was made from payload
+// with the merchant's current or previous webhook secret.
+func (m *merchant) verify(payload []byte, signature string) bool {
+	if VerifyWebhookSignature(payload, signature, m.config.WebhookSecret) == nil {
+		return true
+	}
+	return m.previousWebhookSecret != "" &&
+		VerifyWebhookSignature(payload, signature, m.previousWebhookSecret) == nil
+}
@@ -0,0 +1,67 @@
+package paychangu
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegistryRotate(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("acme", Config{SecretKey: "sec-test-1", WebhookSecret: "whsec-1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Rotate("acme", "sec-test-2", "whsec-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Rotate("acme", "sec-test-3", "whsec-3"); err != nil {
+		t.Fatal(err)
+	}
+	m := r.merchants["acme"]
+	if m.config.WebhookSecret != "whsec-3" || m.previousWebhookSecret != "whsec-2" {
+		t.Errorf("secrets = %q and %q, want whsec-3 and whsec-2", m.config.WebhookSecret, m.previousWebhookSecret)
+	}
+
+	r.Remove("acme")
+	if err := r.Rotate("acme", "sec-test-4", "whsec-4"); !errors.Is(err, ErrUnknownMerchant) {
+		t.Fatalf("Rotate after Remove: err = %v, want ErrUnknownMerchant", err)
+	}
+	if _, err := r.Client("acme"); !errors.Is(err, ErrUnknownMerchant) {
+		t.Errorf("Rotate brought back a removed merchant: err = %v", err)
+	}
+}
+
+func TestRegistryScopesIdempotencyKeys(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"transaction":{"charge_id":"PAYOUT-1","status":"pending"}}}`))
+	}))
+	defer server.Close()
+
+	r := NewRegistry(WithIdempotencyStore(NewMemoryIdempotencyStore()))
+	for _, id := range []string{"acme", "globex"} {
+		if err := r.Register(id, Config{SecretKey: "sec-test-" + id, BaseURL: server.URL}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := MobileMoneyPayoutRequest{ChargeID: "PAYOUT-1", Mobile: "0991234567", Amount: 1000}
+	for _, id := range []string{"acme", "globex", "acme"} {
+		client, err := r.Client(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.InitiateMobileMoneyPayout(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each merchant's payout is sent once; the repeat is replayed.
+	if calls.Load() != 2 {
+		t.Errorf("sent %d payouts, want 2", calls.Load())
+	}
+}
@@ -0,0 +1,68 @@
+package paychangu
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryMiddleware retries failed GET requests up to max times when the
+// API responds with 429 or a 5xx status, or the request fails to send.
+// Requests that move money (POST) are never retried. Waits back off
+// exponentially and honour a Retry-After header. The number of retries
+// is reported in CallStats.Retries.
+func RetryMiddleware(max int) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			stats := callStats(req.Context())
+			attempt := 0
+			for {
+				resp, err := next.Do(req)
+				if attempt >= max || !shouldRetry(req, resp, err) {
+					return resp, err
+				}
+
+				wait := retryDelay(attempt, resp)
+				if resp != nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				attempt++
+				stats.Retries++
+
+				select {
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				case <-time.After(wait):
+				}
+			}
+		})
+	}
+}
+
+// shouldRetry reports whether the outcome of req is worth another attempt.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryDelay returns how long to wait before retry number attempt+1,
+// honouring a Retry-After header in seconds when the API sends one.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+
+	if attempt >= 5 {
+		return 5 * time.Second
+	}
+	return 200 * time.Millisecond << attempt
+}
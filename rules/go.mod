@@ -0,0 +1,10 @@
+module github.com/santinalbrowns/paychangu/rules
+
+go 1.23.2
+
+require (
+	github.com/santinalbrowns/paychangu v0.0.0-00010101000000-000000000000
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/santinalbrowns/paychangu => ../
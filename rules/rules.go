@@ -0,0 +1,296 @@
+// Package rules enforces payout velocity limits and blocklists inside
+// the SDK, so that a compromised internal service cannot drain the
+// wallet through it. An Engine is installed on a client with
+// paychangu.WithPayoutGuard and checks every payout before it is sent.
+//
+// Rules are declared in code:
+//
+//	engine := rules.New(rules.Rules{
+//	    MaxAmount:            500000,
+//	    DailyCapPerRecipient: 1000000,
+//	    MaxCountPerHour:      100,
+//	    BlockedMobiles:       []string{"0881234567"},
+//	})
+//	client := paychangu.New("your_secret_key", paychangu.WithPayoutGuard(engine))
+//
+// or in YAML:
+//
+//	max_amount: 500000
+//	daily_cap_per_recipient: 1000000
+//	max_count_per_hour: 100
+//	currencies:
+//	  USD: {max_amount: 500, daily_cap_per_recipient: 1000}
+//	blocked_mobiles: ["0881234567"]
+//	blocked_accounts: ["1000000010"]
+//
+// Violations are returned as a *Violation wrapping one of the Err
+// sentinels and paychangu.ErrPayoutRefused, so callers can use
+// errors.Is or errors.As.
+//
+// The velocity limits are kept in memory and are per process: with
+// several processes, or after a restart, each Engine only counts the
+// payouts it allowed itself. Divide the limits by the number of
+// processes, or route payouts through a single one, to bound the total.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Sentinel errors wrapped by Violation, one per rule.
+var (
+	ErrInvalidAmount = errors.New("rules: payout amount must be a positive number")
+	ErrMaxAmount     = errors.New("rules: payout exceeds the maximum amount")
+	ErrDailyCap      = errors.New("rules: payout exceeds the daily cap for the recipient")
+	ErrHourlyCount   = errors.New("rules: too many payouts in the last hour")
+	ErrBlocked       = errors.New("rules: recipient is blocked")
+)
+
+// Violation is returned when a payout breaks a rule.
+type Violation struct {
+	// Err is the sentinel of the rule broken.
+	Err error
+
+	ChargeID  string
+	Recipient string
+	Currency  string
+
+	// Limit is the limit of the rule and Actual the value that
+	// broke it, for amount and count rules.
+	Limit  float64
+	Actual float64
+}
+
+// Error implements the error interface.
+func (v *Violation) Error() string {
+	switch {
+	case errors.Is(v.Err, ErrBlocked):
+		return fmt.Sprintf("%v: payout %s to %s", v.Err, v.ChargeID, v.Recipient)
+	case errors.Is(v.Err, ErrInvalidAmount):
+		return fmt.Sprintf("%v: payout %s to %s: %g", v.Err, v.ChargeID, v.Recipient, v.Actual)
+	}
+	msg := fmt.Sprintf("%v: payout %s to %s: %g > %g", v.Err, v.ChargeID, v.Recipient, v.Actual, v.Limit)
+	if v.Currency != "" && !errors.Is(v.Err, ErrHourlyCount) {
+		msg += " " + v.Currency
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel of the rule broken, and
+// paychangu.ErrPayoutRefused.
+func (v *Violation) Unwrap() []error {
+	return []error{v.Err, paychangu.ErrPayoutRefused}
+}
+
+// Rules are the limits applied to payouts. Zero values disable a rule.
+type Rules struct {
+	// MaxAmount is the largest amount of a single payout.
+	MaxAmount float64 `yaml:"max_amount"`
+
+	// DailyCapPerRecipient is the most that one mobile number
+	// or bank account may receive in any 24 hours.
+	DailyCapPerRecipient float64 `yaml:"daily_cap_per_recipient"`
+
+	// Currencies holds the amount limits of payouts in the currencies
+	// it lists, by currency code, in place of MaxAmount and
+	// DailyCapPerRecipient.
+	Currencies map[string]Limits `yaml:"currencies"`
+
+	// MaxCountPerHour is the most payouts sent in any hour,
+	// in all currencies.
+	MaxCountPerHour int `yaml:"max_count_per_hour"`
+
+	// BlockedMobiles are mobile numbers that may not be paid.
+	// Numbers match regardless of formatting and of a +265 prefix.
+	BlockedMobiles []string `yaml:"blocked_mobiles"`
+
+	// BlockedAccounts are bank account numbers that may not be paid.
+	BlockedAccounts []string `yaml:"blocked_accounts"`
+}
+
+// Limits are the amount limits of payouts in one currency.
+// Zero values disable a limit.
+type Limits struct {
+	MaxAmount            float64 `yaml:"max_amount"`
+	DailyCapPerRecipient float64 `yaml:"daily_cap_per_recipient"`
+}
+
+// limits returns the amount limits of payouts in currency.
+func (r Rules) limits(currency string) Limits {
+	for code, limits := range r.Currencies {
+		if strings.EqualFold(code, currency) {
+			return limits
+		}
+	}
+	return Limits{MaxAmount: r.MaxAmount, DailyCapPerRecipient: r.DailyCapPerRecipient}
+}
+
+// Load reads Rules from YAML.
+func Load(r io.Reader) (Rules, error) {
+	var rules Rules
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && err != io.EOF {
+		return Rules{}, fmt.Errorf("failed to read rules: %w", err)
+	}
+	return rules, nil
+}
+
+// LoadFile reads Rules from a YAML file.
+func LoadFile(path string) (Rules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// Engine checks payouts against Rules. It remembers the payouts it
+// allowed in the last 24 hours, in memory, to enforce the velocity
+// rules; every allowed payout counts, whether or not it succeeds. The
+// limits therefore apply per process and start afresh on restart. It
+// is safe for concurrent use and implements paychangu.PayoutGuard.
+type Engine struct {
+	rules   Rules
+	blocked map[string]bool
+
+	mu      sync.Mutex
+	history []record
+}
+
+// record is a payout allowed by the engine.
+type record struct {
+	at        time.Time
+	recipient string
+	currency  string
+	amount    float64
+}
+
+// Payout operations, as in paychangu.PayoutCheck.
+const (
+	opMobileMoney = "InitiateMobileMoneyPayout"
+	opBank        = "InitiateBankPayout"
+)
+
+// New creates an Engine enforcing rules.
+func New(rules Rules) *Engine {
+	e := &Engine{rules: rules, blocked: make(map[string]bool)}
+	for _, mobile := range rules.BlockedMobiles {
+		e.blocked[recipientKey(opMobileMoney, mobile)] = true
+	}
+	for _, account := range rules.BlockedAccounts {
+		e.blocked[recipientKey(opBank, account)] = true
+	}
+	return e
+}
+
+// CheckPayout implements paychangu.PayoutGuard. It returns a
+// *Violation if the payout breaks a rule and records it otherwise.
+// Amounts that are not positive finite numbers are always refused,
+// since they would slip past or corrupt the amount limits. Mobile
+// numbers and bank accounts are told apart by the payout's Operation,
+// so a blocked or capped mobile number never matches an account number
+// with the same digits.
+func (e *Engine) CheckPayout(ctx context.Context, payout paychangu.PayoutCheck) error {
+	recipient := recipientKey(payout.Operation, payout.Recipient)
+	currency := strings.ToUpper(strings.TrimSpace(payout.Currency))
+	limits := e.rules.limits(currency)
+	violation := func(err error, limit, actual float64) error {
+		return &Violation{Err: err, ChargeID: payout.ChargeID, Recipient: payout.Recipient, Currency: currency, Limit: limit, Actual: actual}
+	}
+
+	if !(payout.Amount > 0) || math.IsInf(payout.Amount, 0) {
+		return violation(ErrInvalidAmount, 0, payout.Amount)
+	}
+	if e.blocked[recipient] {
+		return violation(ErrBlocked, 0, 0)
+	}
+	if limits.MaxAmount > 0 && payout.Amount > limits.MaxAmount {
+		return violation(ErrMaxAmount, limits.MaxAmount, payout.Amount)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	e.prune(now)
+
+	if e.rules.MaxCountPerHour > 0 {
+		count := 1
+		for _, r := range e.history {
+			if now.Sub(r.at) < time.Hour {
+				count++
+			}
+		}
+		if count > e.rules.MaxCountPerHour {
+			return violation(ErrHourlyCount, float64(e.rules.MaxCountPerHour), float64(count))
+		}
+	}
+
+	if limits.DailyCapPerRecipient > 0 {
+		total := payout.Amount
+		for _, r := range e.history {
+			if r.recipient == recipient && r.currency == currency {
+				total += r.amount
+			}
+		}
+		if total > limits.DailyCapPerRecipient {
+			return violation(ErrDailyCap, limits.DailyCapPerRecipient, total)
+		}
+	}
+
+	e.history = append(e.history, record{at: now, recipient: recipient, currency: currency, amount: payout.Amount})
+	return nil
+}
+
+// prune forgets payouts older than 24 hours. e.mu must be held.
+func (e *Engine) prune(now time.Time) {
+	i := 0
+	for i < len(e.history) && now.Sub(e.history[i].at) >= 24*time.Hour {
+		i++
+	}
+	e.history = e.history[i:]
+}
+
+// recipientKey identifies the recipient of a payout made by operation:
+// its normalized mobile number or account number, prefixed with the
+// operation.
+func recipientKey(operation, recipient string) string {
+	if operation == opMobileMoney {
+		return operation + ":" + normalizeMobile(recipient)
+	}
+	return operation + ":" + normalizeAccount(recipient)
+}
+
+// normalizeMobile reduces a mobile number to its national
+// significant digits, e.g. "+265 88 123 4567" to "881234567".
+func normalizeMobile(mobile string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, mobile)
+	if len(digits) == 12 && strings.HasPrefix(digits, "265") {
+		digits = digits[3:]
+	}
+	return strings.TrimPrefix(digits, "0")
+}
+
+// normalizeAccount removes spaces and dashes from an account number.
+func normalizeAccount(account string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(account))
+}
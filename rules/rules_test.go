@@ -0,0 +1,76 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+func mobile(chargeID, number string, amount float64) paychangu.PayoutCheck {
+	return paychangu.PayoutCheck{Operation: "InitiateMobileMoneyPayout", ChargeID: chargeID, Amount: amount, Currency: "MWK", Recipient: number}
+}
+
+func bank(chargeID, account string, amount float64) paychangu.PayoutCheck {
+	return paychangu.PayoutCheck{Operation: "InitiateBankPayout", ChargeID: chargeID, Amount: amount, Currency: "MWK", Recipient: account}
+}
+
+func TestEngineSeparatesMobilesAndAccounts(t *testing.T) {
+	ctx := context.Background()
+	engine := New(Rules{DailyCapPerRecipient: 1000, BlockedMobiles: []string{"0881234567"}})
+
+	if err := engine.CheckPayout(ctx, mobile("PAYOUT-1", "+265 88 123 4567", 100)); !errors.Is(err, ErrBlocked) {
+		t.Errorf("blocked mobile: err = %v, want ErrBlocked", err)
+	}
+	if err := engine.CheckPayout(ctx, bank("PAYOUT-2", "881234567", 100)); err != nil {
+		t.Errorf("account with the digits of a blocked mobile: %v", err)
+	}
+
+	// The cap of an account is not used up by a mobile number
+	// with the same digits.
+	if err := engine.CheckPayout(ctx, mobile("PAYOUT-3", "0991234567", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.CheckPayout(ctx, bank("PAYOUT-4", "991234567", 900)); err != nil {
+		t.Errorf("account after a mobile payout: %v", err)
+	}
+	if err := engine.CheckPayout(ctx, mobile("PAYOUT-5", "0991234567", 1)); !errors.Is(err, ErrDailyCap) {
+		t.Errorf("mobile over its cap: err = %v, want ErrDailyCap", err)
+	}
+}
+
+func TestEngineCurrencyLimits(t *testing.T) {
+	ctx := context.Background()
+	rules, err := Load(strings.NewReader(`
+max_amount: 500000
+daily_cap_per_recipient: 1000000
+currencies:
+  usd: {max_amount: 500, daily_cap_per_recipient: 800}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := New(rules)
+
+	if err := engine.CheckPayout(ctx, mobile("PAYOUT-1", "0991234567", 400000)); err != nil {
+		t.Errorf("MWK payout within the default limits: %v", err)
+	}
+
+	usd := mobile("PAYOUT-2", "0991234567", 600)
+	usd.Currency = "USD"
+	var v *Violation
+	if err := engine.CheckPayout(ctx, usd); !errors.As(err, &v) || !errors.Is(err, ErrMaxAmount) || v.Limit != 500 || v.Currency != "USD" {
+		t.Fatalf("USD payout over its maximum: err = %v", err)
+	}
+
+	// Daily totals are kept per currency.
+	usd.Amount = 500
+	if err := engine.CheckPayout(ctx, usd); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.CheckPayout(ctx, usd); !errors.Is(err, ErrDailyCap) {
+		t.Errorf("second USD payout: err = %v, want ErrDailyCap", err)
+	}
+}
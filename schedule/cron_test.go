@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"* * * FOO *",
+		"@every 5m",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q): want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"*/15 * * * *", date(2026, 10, 19, 10, 7), date(2026, 10, 19, 10, 15)},
+		{"*/15 * * * *", date(2026, 10, 19, 10, 14).Add(30 * time.Second), date(2026, 10, 19, 10, 15)},
+		{"*/15 * * * *", date(2026, 10, 19, 10, 15), date(2026, 10, 19, 10, 30)},
+		{"5/15 * * * *", date(2026, 10, 19, 10, 21), date(2026, 10, 19, 10, 35)},
+		{"5/15 * * * *", date(2026, 10, 19, 10, 50), date(2026, 10, 19, 11, 5)},
+		{"0 9-17/4 * * *", date(2026, 10, 19, 13, 0), date(2026, 10, 19, 17, 0)},
+
+		// Fridays at 17:00, from a Monday and from a Friday at 17:00.
+		{"0 17 * * FRI", date(2026, 10, 19, 10, 0), date(2026, 10, 23, 17, 0)},
+		{"0 17 * * FRI", date(2026, 10, 23, 17, 0), date(2026, 10, 30, 17, 0)},
+		{"0 0 * * MON-FRI", date(2026, 10, 23, 12, 0), date(2026, 10, 26, 0, 0)},
+
+		// Sunday is both 0 and 7.
+		{"0 0 * * 0", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+		{"0 0 * * 7", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+		{"0 0 * * sun", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+
+		// With both day fields restricted, either matches.
+		{"0 0 1,15 * MON", date(2026, 10, 20, 0, 0), date(2026, 10, 26, 0, 0)},
+		{"0 0 1,15 * MON", date(2026, 10, 31, 0, 0), date(2026, 11, 1, 0, 0)},
+		{"0 0 15 * *", date(2026, 10, 20, 0, 0), date(2026, 11, 15, 0, 0)},
+
+		// Months by number and name, and days missing from some months.
+		{"0 9 * JAN-MAR *", date(2026, 10, 19, 0, 0), date(2027, 1, 1, 9, 0)},
+		{"0 0 31 * *", date(2026, 10, 31, 0, 0), date(2026, 12, 31, 0, 0)},
+		{"0 0 29 2 *", date(2026, 3, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+
+		{"@hourly", date(2026, 10, 19, 10, 7), date(2026, 10, 19, 11, 0)},
+		{"@monthly", date(2026, 10, 19, 10, 7), date(2026, 11, 1, 0, 0)},
+		{"@yearly", date(2026, 10, 19, 10, 7), date(2027, 1, 1, 0, 0)},
+
+		// Never.
+		{"0 0 30 2 *", date(2026, 10, 19, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := c.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q after %s: got %s, want %s", tt.spec, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, loc)
+	}
+
+	// Clocks go back from 02:00 to 01:00 on November 1st; the
+	// repeated 01:30 matches once.
+	c, err := ParseCron("30 1 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := c.Next(date(11, 1, 0, 0))
+	if want := time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC); !first.Equal(want) {
+		t.Errorf("first 01:30 = %s, want %s", first, want)
+	}
+	if got, want := c.Next(first), time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("after the first 01:30: got %s, want %s", got, want)
+	}
+
+	// Hours after the repeated one still match.
+	c, err = ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Next(first), time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("hourly after the first 01:30: got %s, want 02:00 EST (%s)", got, want)
+	}
+
+	// Clocks go forward from 02:00 to 03:00 on March 8th; 02:30
+	// does not occur that day.
+	c, err = ParseCron("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Next(date(3, 7, 3, 0)), date(3, 9, 2, 30); !got.Equal(want) {
+		t.Errorf("02:30 around the skipped hour: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+// Package schedule makes recurring payouts, such as weekly agent
+// commissions. A Template holds the payout and a cron schedule; the
+// Scheduler makes the payout at every occurrence of the schedule and
+// records each occurrence as a Run.
+//
+// Every run has its own ChargeID, derived from the template ID and the
+// time of the occurrence, so retrying a run, or re-running it after a
+// crash, cannot pay twice. Occurrences missed while the scheduler was
+// down are skipped, or caught up if the template asks for it.
+//
+// Example Usage:
+//
+//	scheduler := &schedule.Scheduler{
+//	    Store:  schedule.NewMemoryStore(),
+//	    Client: client,
+//	    OnRun: func(ctx context.Context, run *schedule.Run) {
+//	        log.Printf("%s %s: %s", run.TemplateID, run.ScheduledAt, run.Status)
+//	    },
+//	}
+//
+//	_, err := scheduler.Add(ctx, &schedule.Template{
+//	    ID:          "agent-042-commission",
+//	    Schedule:    "0 17 * * FRI",
+//	    Timezone:    "Africa/Blantyre",
+//	    MobileMoney: &payoutReq,
+//	})
+//
+//	go scheduler.Run(ctx)
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Errors returned by the Store.
+var (
+	ErrNotFound = errors.New("schedule: not found")
+
+	// ErrConflict is returned when a template was changed
+	// by someone else since it was read.
+	ErrConflict = errors.New("schedule: concurrent update")
+)
+
+// Template is a payout made on a schedule.
+type Template struct {
+	// ID identifies the template and prefixes the ChargeIDs of its runs.
+	// One is generated if it is empty.
+	ID string `json:"id"`
+
+	// Schedule is a cron expression; see Cron.
+	Schedule string `json:"schedule"`
+
+	// Timezone is the IANA time zone the schedule is read in,
+	// e.g. "Africa/Blantyre". It defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+
+	// MobileMoney or Bank holds the payout to make. Its ChargeID is
+	// replaced for every run.
+	MobileMoney *paychangu.MobileMoneyPayoutRequest `json:"mobile_money,omitempty"`
+	Bank        *paychangu.BankPayoutRequest        `json:"bank,omitempty"`
+
+	// CatchUp makes the scheduler run the occurrences it missed while it
+	// was down. Otherwise they are recorded as skipped.
+	CatchUp bool `json:"catch_up,omitempty"`
+
+	// Paused templates are not run, and occurrences passing while
+	// paused are treated as missed when the template is resumed.
+	Paused bool `json:"paused,omitempty"`
+
+	// Next is the next occurrence to run.
+	Next time.Time `json:"next"`
+
+	// Version is incremented by the Store on every update.
+	Version int `json:"version"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// cron parses the schedule of t and loads its time zone.
+func (t *Template) cron() (*Cron, *time.Location, error) {
+	c, err := ParseCron(t.Schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.UTC
+	if t.Timezone != "" {
+		if loc, err = time.LoadLocation(t.Timezone); err != nil {
+			return nil, nil, fmt.Errorf("schedule: invalid time zone %q: %w", t.Timezone, err)
+		}
+	}
+	return c, loc, nil
+}
+
+// ChargeID returns the ChargeID of the run of t scheduled at at.
+func (t *Template) ChargeID(at time.Time) string {
+	return t.ID + "-" + at.UTC().Format("200601021504")
+}
+
+// Amount returns the amount of the payout.
+func (t *Template) Amount() float64 {
+	if t.Bank != nil {
+		return t.Bank.Amount
+	}
+	if t.MobileMoney != nil {
+		return t.MobileMoney.Amount
+	}
+	return 0
+}
+
+// RunStatus is the status of a run.
+type RunStatus string
+
+// Run statuses.
+const (
+	// RunRunning runs are being made, or were interrupted by a crash
+	// and are resumed by the next Tick.
+	RunRunning RunStatus = "running"
+
+	// RunSucceeded runs were accepted by PayChangu. PayoutStatus holds
+	// the status PayChangu reported for the payout.
+	RunSucceeded RunStatus = "succeeded"
+
+	// RunFailed runs were rejected, or still failing after every retry.
+	RunFailed RunStatus = "failed"
+
+	// RunSkipped runs were missed while the scheduler was down
+	// or the template paused.
+	RunSkipped RunStatus = "skipped"
+)
+
+// Run is one occurrence of a template.
+type Run struct {
+	// ID is the ChargeID of the payout made by the run.
+	ID         string    `json:"id"`
+	TemplateID string    `json:"template_id"`
+	Amount     float64   `json:"amount"`
+	Status     RunStatus `json:"status"`
+
+	// Attempts is the number of times the payout was sent.
+	Attempts int `json:"attempts"`
+
+	// Error is the error of the last attempt, if it failed.
+	Error string `json:"error,omitempty"`
+
+	// RefID and PayoutStatus are reported by PayChangu.
+	RefID        string `json:"ref_id,omitempty"`
+	PayoutStatus string `json:"payout_status,omitempty"`
+
+	ScheduledAt time.Time `json:"scheduled_at"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at,omitempty"`
+}
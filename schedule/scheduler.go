@@ -0,0 +1,382 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// Client is the part of the PayChangu client used by a Scheduler.
+type Client interface {
+	InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error)
+	InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error)
+	GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error)
+	GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error)
+}
+
+// Scheduler runs templates when they are due. Each run is saved as
+// running before its payout is sent, so a run interrupted by a crash is
+// resumed by the next Tick, which looks its payout up before sending
+// it again (see paychangu.IsTransient). Retries do the same.
+//
+// Run only one Scheduler per Store: runs are not claimed before their
+// payout is sent, so two schedulers ticking at once may both send it.
+// Configure the client with paychangu.WithIdempotencyStore, shared by
+// every process, to make such a duplicate fail instead of paying twice.
+type Scheduler struct {
+	// Store persists the templates and runs. It is required.
+	Store Store
+
+	// Client sends payouts to PayChangu. It is required.
+	Client Client
+
+	// Retries is the number of times a payout failing with a network
+	// error, a 429 or a 5xx status is retried. It defaults to 3; set it
+	// negative to disable retries.
+	Retries int
+
+	// RetryDelay is the wait before the first retry, doubled for each
+	// retry after it. It defaults to 30 seconds.
+	RetryDelay time.Duration
+
+	// MissedAfter is how late an occurrence may be run. Occurrences
+	// further in the past, missed while the scheduler was down, are
+	// skipped unless their template has CatchUp set. It defaults to
+	// one hour.
+	MissedAfter time.Duration
+
+	// MaxCatchUp is the most missed occurrences of a template with
+	// CatchUp set that one tick runs late, oldest first. Those beyond
+	// it are recorded as skipped, so a long outage does not release a
+	// burst of payouts. It defaults to 10.
+	MaxCatchUp int
+
+	// Interval is the time between ticks of Run. It defaults to one minute.
+	Interval time.Duration
+
+	// Logger, if set, receives a record of every finished run
+	// and the errors of individual templates.
+	Logger *slog.Logger
+
+	// OnRun, if set, is called with every finished run, including
+	// skipped ones, to report it.
+	OnRun func(ctx context.Context, run *Run)
+}
+
+// Add validates t, sets its first occurrence and saves it.
+func (s *Scheduler) Add(ctx context.Context, t *Template) (*Template, error) {
+	if (t.MobileMoney == nil) == (t.Bank == nil) {
+		return nil, fmt.Errorf("schedule: template needs exactly one of MobileMoney and Bank")
+	}
+	if t.ID == "" {
+		t.ID = paychangu.NewReference("SCHED")
+	}
+	c, loc, err := t.cron()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	t.Next = c.Next(now.In(loc))
+	if t.Next.IsZero() {
+		return nil, fmt.Errorf("schedule: %q never runs", t.Schedule)
+	}
+	t.Version = 0
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	if err := s.Store.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Pause stops a template from running until it is resumed.
+func (s *Scheduler) Pause(ctx context.Context, id string) (*Template, error) {
+	return s.setPaused(ctx, id, true)
+}
+
+// Resume restarts a paused template. Occurrences that passed while it
+// was paused are skipped or caught up as if the scheduler had been down.
+func (s *Scheduler) Resume(ctx context.Context, id string) (*Template, error) {
+	return s.setPaused(ctx, id, false)
+}
+
+// setPaused sets the Paused flag of a template.
+func (s *Scheduler) setPaused(ctx context.Context, id string, paused bool) (*Template, error) {
+	t, err := s.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t.Paused = paused
+	t.UpdatedAt = time.Now().UTC()
+	return t, s.Store.Update(ctx, t)
+}
+
+// Run ticks every Interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Tick(ctx); err != nil && ctx.Err() == nil && s.Logger != nil {
+			s.Logger.Error("payout scheduler tick failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs every occurrence of every unpaused template that is due,
+// oldest first. It returns the errors of the templates it could not
+// advance, joined; failed payouts are recorded in their runs instead.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	templates, err := s.Store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, t := range templates {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if t.Paused {
+			continue
+		}
+		if err := s.advance(ctx, t, now); err != nil {
+			if s.Logger != nil {
+				s.Logger.Warn("scheduled payout not advanced", "template", t.ID, "error", err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// advance runs the occurrences of t due by now and moves t to its
+// next occurrence after each.
+func (s *Scheduler) advance(ctx context.Context, t *Template, now time.Time) error {
+	c, loc, err := t.cron()
+	if err != nil {
+		return err
+	}
+
+	late := 0
+	for !t.Next.IsZero() && !t.Next.After(now) {
+		skip := ""
+		if missed := now.Sub(t.Next); missed > s.missedAfter() {
+			late++
+			switch {
+			case !t.CatchUp:
+				skip = fmt.Sprintf("missed by %s", missed.Round(time.Minute))
+			case late > s.maxCatchUp():
+				skip = fmt.Sprintf("missed by %s, beyond %d caught up", missed.Round(time.Minute), s.maxCatchUp())
+			}
+		}
+		if err := s.run(ctx, t, t.Next, skip); err != nil {
+			return err
+		}
+		t.Next = c.Next(t.Next.In(loc))
+		t.UpdatedAt = time.Now().UTC()
+		if err := s.Store.Update(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run makes the run of t scheduled at at, unless it already finished.
+// A new run is recorded as skipped instead, with skip as its error, if
+// skip is not empty.
+func (s *Scheduler) run(ctx context.Context, t *Template, at time.Time, skip string) error {
+	chargeID := t.ChargeID(at)
+	run, err := s.Store.GetRun(ctx, chargeID)
+	resumed := err == nil
+	switch {
+	case resumed && run.Status != RunRunning:
+		// Finished before a crash kept t from moving on.
+		return nil
+	case resumed:
+	case errors.Is(err, ErrNotFound):
+		run = &Run{
+			ID:          chargeID,
+			TemplateID:  t.ID,
+			Amount:      t.Amount(),
+			Status:      RunRunning,
+			ScheduledAt: at.UTC(),
+			StartedAt:   time.Now().UTC(),
+		}
+		if skip != "" {
+			run.Status = RunSkipped
+			run.Error = skip
+			return s.finish(ctx, run)
+		}
+		if err := s.Store.SaveRun(ctx, run); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if err := s.execute(ctx, t, run, resumed); err != nil {
+		return err
+	}
+	return s.finish(ctx, run)
+}
+
+// maxCatchUp returns MaxCatchUp or its default.
+func (s *Scheduler) maxCatchUp() int {
+	if s.MaxCatchUp > 0 {
+		return s.MaxCatchUp
+	}
+	return 10
+}
+
+// missedAfter returns MissedAfter or its default.
+func (s *Scheduler) missedAfter() time.Duration {
+	if s.MissedAfter > 0 {
+		return s.MissedAfter
+	}
+	return time.Hour
+}
+
+// execute makes the payout of run, retrying transient failures, and
+// sets the run's status. It only returns an error if ctx is done
+// during a retry, leaving the run running.
+func (s *Scheduler) execute(ctx context.Context, t *Template, run *Run, check bool) error {
+	retries := s.Retries
+	if retries == 0 {
+		retries = 3
+	}
+	delay := s.RetryDelay
+	if delay <= 0 {
+		delay = 30 * time.Second
+	}
+
+	for retry := 0; ; retry++ {
+		err := s.attempt(ctx, t, run, check)
+		if err == nil {
+			run.Status = RunSucceeded
+			run.Error = ""
+			switch status := paychangu.NormalizeStatus(run.PayoutStatus); status {
+			case paychangu.StatusFailed, paychangu.StatusReversed:
+				run.Status = RunFailed
+				run.Error = "payout " + status
+			}
+			return nil
+		}
+
+		run.Error = err.Error()
+		if !paychangu.IsTransient(err) || retry >= retries {
+			run.Status = RunFailed
+			return nil
+		}
+		if err := s.Store.SaveRun(ctx, run); err != nil && s.Logger != nil {
+			s.Logger.Warn("scheduled payout run not saved", "run", run.ID, "error", err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+		check = true
+	}
+}
+
+// attempt sends the payout of run. If check is set, as when an earlier
+// attempt may have reached PayChangu, lookup is tried first and the
+// payout is sent only if it finds nothing.
+func (s *Scheduler) attempt(ctx context.Context, t *Template, run *Run, check bool) error {
+	if check {
+		err := s.lookup(ctx, t, run)
+		var apiErr *paychangu.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			return err
+		}
+	}
+
+	run.Attempts++
+	switch {
+	case t.MobileMoney != nil:
+		request := *t.MobileMoney
+		request.ChargeID = run.ID
+		resp, err := s.Client.InitiateMobileMoneyPayoutContext(ctx, request)
+		if err != nil {
+			return err
+		}
+		run.RefID, run.PayoutStatus = resp.Data.Transaction.RefID, resp.Data.Transaction.Status
+	case t.Bank != nil:
+		request := *t.Bank
+		request.ChargeID = run.ID
+		resp, err := s.Client.InitiateBankPayoutContext(ctx, request)
+		if err != nil {
+			return err
+		}
+		run.RefID, run.PayoutStatus = resp.Data.Transaction.RefID, resp.Data.Transaction.Status
+	default:
+		return fmt.Errorf("schedule: template %s has no payout", t.ID)
+	}
+	return nil
+}
+
+// lookup fills in the RefID and PayoutStatus of run from the payout
+// details endpoint.
+func (s *Scheduler) lookup(ctx context.Context, t *Template, run *Run) error {
+	switch {
+	case t.MobileMoney != nil:
+		d, err := s.Client.GetMobileMoneyPayoutDetailsContext(ctx, run.ID)
+		if err != nil {
+			return err
+		}
+		run.RefID, run.PayoutStatus = d.RefID, d.Status
+	case t.Bank != nil:
+		d, err := s.Client.GetBankPayoutDetailsContext(ctx, run.ID)
+		if err != nil {
+			return err
+		}
+		run.RefID, run.PayoutStatus = d.RefID, d.Status
+	default:
+		return fmt.Errorf("schedule: template %s has no payout", t.ID)
+	}
+	return nil
+}
+
+// finish saves a finished run and reports it.
+func (s *Scheduler) finish(ctx context.Context, run *Run) error {
+	run.FinishedAt = time.Now().UTC()
+	if err := s.Store.SaveRun(ctx, run); err != nil {
+		return err
+	}
+
+	if s.Logger != nil {
+		s.Logger.Info("scheduled payout run",
+			"template", run.TemplateID,
+			"run", run.ID,
+			"scheduled_at", run.ScheduledAt,
+			"status", run.Status,
+			"attempts", run.Attempts,
+			"error", run.Error,
+		)
+	}
+	if s.OnRun != nil {
+		s.OnRun(ctx, run)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+)
+
+// fakeClient is a Client for mobile money payouts. Each call to
+// initiate a payout fails with the next error from errs, if any; if
+// lost is set, the payout reaches PayChangu even though the call fails.
+type fakeClient struct {
+	mu      sync.Mutex
+	errs    []error
+	lost    bool
+	status  string
+	sent    map[string]int
+	known   map[string]bool
+	lookups int
+}
+
+func (c *fakeClient) InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sent == nil {
+		c.sent = make(map[string]int)
+		c.known = make(map[string]bool)
+	}
+	c.sent[request.ChargeID]++
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if c.lost {
+			c.known[request.ChargeID] = true
+		}
+		return nil, err
+	}
+	c.known[request.ChargeID] = true
+	resp := &paychangu.MobileMoneyPayoutResponse{Status: "success"}
+	resp.Data.Transaction.ChargeID = request.ChargeID
+	resp.Data.Transaction.Status = c.payoutStatus()
+	return resp, nil
+}
+
+func (c *fakeClient) InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeClient) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups++
+	if !c.known[chargeID] {
+		return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+	}
+	return &paychangu.PayoutTransactionDetails{ChargeID: chargeID, Status: c.payoutStatus()}, nil
+}
+
+func (c *fakeClient) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error) {
+	return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+}
+
+func (c *fakeClient) payoutStatus() string {
+	if c.status == "" {
+		return "pending"
+	}
+	return c.status
+}
+
+// sends returns the number of payouts sent.
+func (c *fakeClient) sends() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, count := range c.sent {
+		n += count
+	}
+	return n
+}
+
+// hourly saves an hourly template whose next occurrence is next.
+func hourly(t *testing.T, store Store, next time.Time, catchUp bool) *Template {
+	t.Helper()
+	template := &Template{
+		ID:          "commission",
+		Schedule:    "0 * * * *",
+		MobileMoney: &paychangu.MobileMoneyPayoutRequest{Mobile: "0991234567", Amount: 1000},
+		CatchUp:     catchUp,
+		Next:        next,
+	}
+	if err := store.Create(context.Background(), template); err != nil {
+		t.Fatal(err)
+	}
+	return template
+}
+
+// statuses returns the number of runs of template in each status.
+func statuses(t *testing.T, store Store, templateID string) map[RunStatus]int {
+	t.Helper()
+	runs, err := store.Runs(context.Background(), templateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[RunStatus]int)
+	for _, run := range runs {
+		counts[run.Status]++
+	}
+	return counts
+}
+
+func TestTickSkipsMissedOccurrences(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	client := &fakeClient{}
+	scheduler := &Scheduler{Store: store, Client: client}
+
+	// Three hours were missed; the current one is due.
+	hour := time.Now().UTC().Truncate(time.Hour)
+	hourly(t, store, hour.Add(-3*time.Hour), false)
+	if err := scheduler.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := statuses(t, store, "commission")
+	if counts[RunSkipped] != 3 || counts[RunSucceeded] != 1 || client.sends() != 1 {
+		t.Errorf("runs = %v with %d payouts, want 3 skipped and 1 succeeded", counts, client.sends())
+	}
+	template, err := store.Get(ctx, "commission")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hour.Add(time.Hour); !template.Next.Equal(want) {
+		t.Errorf("next = %s, want %s", template.Next, want)
+	}
+
+	// Nothing is due until the next hour.
+	if err := scheduler.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if client.sends() != 1 {
+		t.Errorf("sent %d payouts, want 1", client.sends())
+	}
+}
+
+func TestTickCatchesUpToMaxCatchUp(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	client := &fakeClient{}
+	scheduler := &Scheduler{Store: store, Client: client, MaxCatchUp: 2}
+
+	hour := time.Now().UTC().Truncate(time.Hour)
+	hourly(t, store, hour.Add(-4*time.Hour), true)
+	if err := scheduler.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := store.Runs(ctx, "commission")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RunStatus{RunSucceeded, RunSucceeded, RunSkipped, RunSkipped, RunSucceeded}
+	if len(runs) != len(want) {
+		t.Fatalf("got %d runs, want %d", len(runs), len(want))
+	}
+	for i, run := range runs {
+		if run.Status != want[i] {
+			t.Errorf("run %d at %s is %s, want %s", i, run.ScheduledAt, run.Status, want[i])
+		}
+	}
+	if client.sends() != 3 {
+		t.Errorf("sent %d payouts, want 3", client.sends())
+	}
+}
+
+func TestTickSkipsPausedTemplates(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	client := &fakeClient{}
+	scheduler := &Scheduler{Store: store, Client: client}
+
+	hourly(t, store, time.Now().UTC().Truncate(time.Hour), false)
+	if _, err := scheduler.Pause(ctx, "commission"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduler.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if client.sends() != 0 {
+		t.Errorf("sent %d payouts for a paused template", client.sends())
+	}
+}
+
+func TestTickResumesInterruptedRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		reached   bool
+		wantSends int
+	}{
+		{name: "payout reached PayChangu", reached: true, wantSends: 0},
+		{name: "payout never sent", reached: false, wantSends: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			client := &fakeClient{known: map[string]bool{}, sent: map[string]int{}}
+			scheduler := &Scheduler{Store: store, Client: client}
+
+			// A crash left the current run running.
+			at := time.Now().UTC().Truncate(time.Hour)
+			template := hourly(t, store, at, false)
+			run := &Run{ID: template.ChargeID(at), TemplateID: template.ID, Status: RunRunning, ScheduledAt: at, StartedAt: at}
+			if err := store.SaveRun(ctx, run); err != nil {
+				t.Fatal(err)
+			}
+			client.known[run.ID] = tt.reached
+
+			if err := scheduler.Tick(ctx); err != nil {
+				t.Fatal(err)
+			}
+			run, err := store.GetRun(ctx, run.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != RunSucceeded || run.PayoutStatus != "pending" {
+				t.Errorf("run is %s with payout %q, want succeeded and pending", run.Status, run.PayoutStatus)
+			}
+			if client.lookups != 1 || client.sends() != tt.wantSends {
+				t.Errorf("%d lookups and %d payouts, want 1 and %d", client.lookups, client.sends(), tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestTickRetries(t *testing.T) {
+	unavailable := &paychangu.APIError{StatusCode: http.StatusServiceUnavailable}
+	tests := []struct {
+		name        string
+		client      *fakeClient
+		wantStatus  RunStatus
+		wantSends   int
+		wantLookups int
+	}{
+		{
+			name:        "recovers",
+			client:      &fakeClient{errs: []error{unavailable}},
+			wantStatus:  RunSucceeded,
+			wantSends:   2,
+			wantLookups: 1,
+		},
+		{
+			name:        "response lost",
+			client:      &fakeClient{errs: []error{unavailable}, lost: true},
+			wantStatus:  RunSucceeded,
+			wantSends:   1,
+			wantLookups: 1,
+		},
+		{
+			name:        "retries exhausted",
+			client:      &fakeClient{errs: []error{unavailable, unavailable, unavailable}},
+			wantStatus:  RunFailed,
+			wantSends:   3,
+			wantLookups: 2,
+		},
+		{
+			name:       "rejected",
+			client:     &fakeClient{errs: []error{&paychangu.APIError{StatusCode: http.StatusBadRequest}}},
+			wantStatus: RunFailed,
+			wantSends:  1,
+		},
+		{
+			name:       "payout failed",
+			client:     &fakeClient{status: "failed"},
+			wantStatus: RunFailed,
+			wantSends:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			scheduler := &Scheduler{Store: store, Client: tt.client, Retries: 2, RetryDelay: time.Millisecond}
+
+			at := time.Now().UTC().Truncate(time.Hour)
+			template := hourly(t, store, at, false)
+			if err := scheduler.Tick(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			run, err := store.GetRun(ctx, template.ChargeID(at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != tt.wantStatus {
+				t.Errorf("run is %s (%s), want %s", run.Status, run.Error, tt.wantStatus)
+			}
+			if tt.client.sends() != tt.wantSends || tt.client.lookups != tt.wantLookups {
+				t.Errorf("%d payouts and %d lookups, want %d and %d", tt.client.sends(), tt.client.lookups, tt.wantSends, tt.wantLookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/internal/sqlutil"
+)
+
+// SQLStore is a Store backed by a database/sql database, such as
+// SQLite (e.g. with modernc.org/sqlite) or PostgreSQL (e.g. with pgx).
+// The caller opens db with the driver of their choice and passes the
+// matching dialect.
+type SQLStore struct {
+	db      *sql.DB
+	dialect paychangu.SQLDialect
+
+	// Table is the name of the table templates are kept in, and the
+	// prefix of the table runs are kept in. It defaults to
+	// "paychangu_schedules".
+	Table string
+}
+
+// NewSQLStore creates an SQLStore using db.
+func NewSQLStore(db *sql.DB, dialect paychangu.SQLDialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect, Table: "paychangu_schedules"}
+}
+
+// runs returns the name of the runs table.
+func (s *SQLStore) runs() string {
+	return s.Table + "_runs"
+}
+
+// CreateTable creates the tables templates and runs are kept in,
+// if they do not exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + s.Table + ` (
+	id VARCHAR(255) PRIMARY KEY,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS ` + s.runs() + ` (
+	id VARCHAR(255) PRIMARY KEY,
+	template_id VARCHAR(255) NOT NULL,
+	data TEXT NOT NULL,
+	scheduled_at TIMESTAMP NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + s.runs() + `_template ON ` + s.runs() + ` (template_id)`,
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create implements Store.
+func (s *SQLStore) Create(ctx context.Context, t *Template) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+s.Table+`
+	(id, version, data, created_at) VALUES (?, ?, ?, ?)`),
+		t.ID, t.Version, string(data), t.CreatedAt)
+	return err
+}
+
+// Get implements Store.
+func (s *SQLStore) Get(ctx context.Context, id string) (*Template, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT data FROM `+s.Table+` WHERE id = ?`), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: template %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t Template
+	return &t, json.Unmarshal([]byte(data), &t)
+}
+
+// Update implements Store.
+func (s *SQLStore) Update(ctx context.Context, t *Template) error {
+	t.Version++
+	data, err := json.Marshal(t)
+	if err != nil {
+		t.Version--
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.Table+`
+	SET version = ?, data = ? WHERE id = ? AND version = ?`),
+		t.Version, string(data), t.ID, t.Version-1)
+	if err != nil {
+		t.Version--
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		t.Version--
+		if _, err := s.Get(ctx, t.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: template %s", ErrConflict, t.ID)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+s.Table+` WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: template %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *SQLStore) List(ctx context.Context) ([]*Template, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM `+s.Table+` ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*Template
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var t Template
+		if err := json.Unmarshal([]byte(data), &t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, &t)
+	}
+	return templates, rows.Err()
+}
+
+// SaveRun implements Store.
+func (s *SQLStore) SaveRun(ctx context.Context, run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE `+s.runs()+` SET data = ? WHERE id = ?`), string(data), run.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO `+s.runs()+`
+	(id, template_id, data, scheduled_at) VALUES (?, ?, ?, ?)`),
+		run.ID, run.TemplateID, string(data), run.ScheduledAt)
+	return err
+}
+
+// GetRun implements Store.
+func (s *SQLStore) GetRun(ctx context.Context, id string) (*Run, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT data FROM `+s.runs()+` WHERE id = ?`), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: run %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	return &run, json.Unmarshal([]byte(data), &run)
+}
+
+// Runs implements Store.
+func (s *SQLStore) Runs(ctx context.Context, templateID string) ([]*Run, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT data FROM `+s.runs()+`
+	WHERE template_id = ? ORDER BY scheduled_at`), templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*Run
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var run Run
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
+
+// rebind rewrites the ? placeholders in query for s's dialect.
+func (s *SQLStore) rebind(query string) string {
+	return sqlutil.Rebind(query, s.dialect == paychangu.DialectDollar)
+}
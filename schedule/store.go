@@ -0,0 +1,175 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Store persists templates and their runs.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Create saves a new template. It fails if the ID is already used.
+	Create(ctx context.Context, t *Template) error
+
+	// Get returns the template with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Template, error)
+
+	// Update saves t if its Version matches the stored one, and
+	// increments t.Version. Otherwise it returns ErrConflict.
+	Update(ctx context.Context, t *Template) error
+
+	// Delete removes a template. Its runs are kept.
+	Delete(ctx context.Context, id string) error
+
+	// List returns every template, oldest first.
+	List(ctx context.Context) ([]*Template, error)
+
+	// SaveRun saves a run, replacing any run with the same ID.
+	SaveRun(ctx context.Context, run *Run) error
+
+	// GetRun returns the run with the given ID, or ErrNotFound.
+	GetRun(ctx context.Context, id string) (*Run, error)
+
+	// Runs returns the runs of a template, oldest first.
+	Runs(ctx context.Context, templateID string) ([]*Run, error)
+}
+
+// MemoryStore is a Store that keeps templates and runs in memory, for
+// tests and single-process use. Everything is lost when the process exits.
+type MemoryStore struct {
+	mu        sync.Mutex
+	templates map[string][]byte
+	runs      map[string]Run
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{templates: make(map[string][]byte), runs: make(map[string]Run)}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(ctx context.Context, t *Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[t.ID]; ok {
+		return fmt.Errorf("template %s already exists", t.ID)
+	}
+	return s.put(t)
+}
+
+// put stores a copy of t. s.mu must be held.
+func (s *MemoryStore) put(t *Template) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	s.templates[t.ID] = data
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.templates[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: template %s", ErrNotFound, id)
+	}
+	var t Template
+	return &t, json.Unmarshal(data, &t)
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(ctx context.Context, t *Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.templates[t.ID]
+	if !ok {
+		return fmt.Errorf("%w: template %s", ErrNotFound, t.ID)
+	}
+	var stored Template
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored.Version != t.Version {
+		return fmt.Errorf("%w: template %s", ErrConflict, t.ID)
+	}
+
+	t.Version++
+	if err := s.put(t); err != nil {
+		t.Version--
+		return err
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[id]; !ok {
+		return fmt.Errorf("%w: template %s", ErrNotFound, id)
+	}
+	delete(s.templates, id)
+	return nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List(ctx context.Context) ([]*Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates := make([]*Template, 0, len(s.templates))
+	for _, data := range s.templates {
+		var t Template
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, &t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].CreatedAt.Before(templates[j].CreatedAt) })
+	return templates, nil
+}
+
+// SaveRun implements Store.
+func (s *MemoryStore) SaveRun(ctx context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs[run.ID] = *run
+	return nil
+}
+
+// GetRun implements Store.
+func (s *MemoryStore) GetRun(ctx context.Context, id string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: run %s", ErrNotFound, id)
+	}
+	return &run, nil
+}
+
+// Runs implements Store.
+func (s *MemoryStore) Runs(ctx context.Context, templateID string) ([]*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []*Run
+	for _, run := range s.runs {
+		if run.TemplateID == templateID {
+			runs = append(runs, &run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ScheduledAt.Before(runs[j].ScheduledAt) })
+	return runs, nil
+}
@@ -0,0 +1,235 @@
+package sqlitetest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/approval"
+)
+
+// directory is an approval.Directory with one Malawian
+// operator and one bank.
+type directory struct{}
+
+func (directory) GetMobileMoneyOperatorsContext(ctx context.Context) ([]paychangu.MobileMoneyOperator, error) {
+	return []paychangu.MobileMoneyOperator{{RefID: "airtel", SupportedCountry: paychangu.SupportedCountry{Name: "Malawi", Currency: "MWK"}}}, nil
+}
+
+func (directory) GetSupportedBanksContext(ctx context.Context, currency string) ([]paychangu.Bank, error) {
+	if currency != "MWK" {
+		return nil, nil
+	}
+	return []paychangu.Bank{{UUID: "bank-1", Name: "National Bank"}}, nil
+}
+
+// forwarder is an approval.Forwarder that records the requests forwarded.
+type forwarder struct {
+	forwarded []string
+}
+
+func (f *forwarder) Forward(ctx context.Context, r *approval.Request) error {
+	f.forwarded = append(f.forwarded, r.ID)
+	return nil
+}
+
+func TestApprovalSQLStore(t *testing.T) {
+	ctx := context.Background()
+	store := approval.NewSQLStore(open(t), paychangu.DialectQuestion)
+	if err := store.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	forwarded := &forwarder{}
+	service := &approval.Service{
+		Store:     store,
+		Policy:    approval.Policy{"mwk": {{Threshold: 100000, Approvals: 2}}},
+		Directory: directory{},
+		Forwarder: forwarded,
+	}
+
+	r, err := service.RequestMobileMoney(ctx, "maker", "", paychangu.NewMobileMoneyPayoutRequest("0991234567", "airtel", 250000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Currency != "MWK" || r.Required != 2 {
+		t.Fatalf("got currency %q requiring %d approvals, want MWK requiring 2", r.Currency, r.Required)
+	}
+	if _, err := service.Approve(ctx, r.ID, "maker"); !errors.Is(err, approval.ErrSelfApproval) {
+		t.Errorf("self approval: got %v, want ErrSelfApproval", err)
+	}
+	if _, err := service.Approve(ctx, r.ID, "checker-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Approve(ctx, r.ID, "checker-1"); !errors.Is(err, approval.ErrDuplicateApproval) {
+		t.Errorf("duplicate approval: got %v, want ErrDuplicateApproval", err)
+	}
+	r, err = service.Approve(ctx, r.ID, "checker-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != approval.StatusForwarded || len(forwarded.forwarded) != 1 {
+		t.Errorf("got status %s with %d forwards, want forwarded once", r.Status, len(forwarded.forwarded))
+	}
+
+	stored, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != approval.StatusForwarded || stored.Version != r.Version {
+		t.Errorf("stored status %s at version %d, want forwarded at %d", stored.Status, stored.Version, r.Version)
+	}
+
+	trail, err := service.Audit(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, entry := range trail {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{approval.ActionRequested, approval.ActionApproved, approval.ActionApproved, approval.ActionForwarded}
+	if len(actions) != len(want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("audit actions = %v, want %v", actions, want)
+		}
+	}
+	if err := store.Append(ctx, trail[1]); !errors.Is(err, approval.ErrConflict) {
+		t.Errorf("appending an existing sequence number: got %v, want ErrConflict", err)
+	}
+}
+
+func TestApprovalCurrency(t *testing.T) {
+	ctx := context.Background()
+	store := approval.NewSQLStore(open(t), paychangu.DialectQuestion)
+	if err := store.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	service := &approval.Service{
+		Store:     store,
+		Policy:    approval.Policy{"MWK": {{Threshold: 0, Approvals: 1}}},
+		Directory: directory{},
+		Forwarder: &forwarder{},
+	}
+
+	_, err := service.RequestMobileMoney(ctx, "maker", "USD", paychangu.NewMobileMoneyPayoutRequest("0991234567", "airtel", 100))
+	if !errors.Is(err, approval.ErrCurrencyMismatch) {
+		t.Errorf("mobile money payout in the wrong currency: got %v, want ErrCurrencyMismatch", err)
+	}
+
+	bank := paychangu.BankPayoutRequest{BankUUID: "bank-1", BankAccountName: "Jane", BankAccountNumber: "1000000010", Amount: 100}
+	if _, err := service.RequestBank(ctx, "maker", "USD", bank); !errors.Is(err, approval.ErrCurrencyMismatch) {
+		t.Errorf("bank payout in the wrong currency: got %v, want ErrCurrencyMismatch", err)
+	}
+
+	service.Directory = usdDirectory{}
+	if _, err := service.RequestBank(ctx, "maker", "usd", bank); !errors.Is(err, approval.ErrNoPolicy) {
+		t.Errorf("payout in a currency without a policy: got %v, want ErrNoPolicy", err)
+	}
+}
+
+// usdDirectory is an approval.Directory whose bank pays out in USD.
+type usdDirectory struct{ directory }
+
+func (usdDirectory) GetSupportedBanksContext(ctx context.Context, currency string) ([]paychangu.Bank, error) {
+	return []paychangu.Bank{{UUID: "bank-1", Name: "National Bank"}}, nil
+}
+
+// gatedForwarder is an approval.Forwarder that fails its first call,
+// then holds each call until release is closed.
+type gatedForwarder struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu        sync.Mutex
+	calls     int
+	forwarded int
+}
+
+func (f *gatedForwarder) Forward(ctx context.Context, r *approval.Request) error {
+	f.mu.Lock()
+	f.calls++
+	first := f.calls == 1
+	f.mu.Unlock()
+	if first {
+		return errors.New("PayChangu unavailable")
+	}
+
+	f.started <- struct{}{}
+	<-f.release
+	f.mu.Lock()
+	f.forwarded++
+	f.mu.Unlock()
+	return nil
+}
+
+func TestApprovalForwardOnce(t *testing.T) {
+	ctx := context.Background()
+	store := approval.NewSQLStore(open(t), paychangu.DialectQuestion)
+	if err := store.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	forwarder := &gatedForwarder{started: make(chan struct{}, 2), release: make(chan struct{})}
+	service := &approval.Service{
+		Store:     store,
+		Policy:    approval.Policy{"MWK": {{Threshold: 1000, Approvals: 1}}},
+		Directory: directory{},
+		Forwarder: forwarder,
+	}
+
+	r, err := service.RequestMobileMoney(ctx, "maker", "MWK", paychangu.NewMobileMoneyPayoutRequest("0991234567", "airtel", 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Approve(ctx, r.ID, "checker"); err == nil {
+		t.Fatal("Approve: want the forwarding error")
+	}
+	stored, err := store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != approval.StatusApproved || stored.LastError == "" {
+		t.Fatalf("after a failed forward: status %s, error %q, want approved with the error", stored.Status, stored.LastError)
+	}
+
+	// Two retries race; the first claims the request and the
+	// second finds it taken.
+	errs := make(chan error, 2)
+	go func() {
+		_, err := service.Forward(ctx, r.ID)
+		errs <- err
+	}()
+	<-forwarder.started
+	if _, err := service.Forward(ctx, r.ID); !errors.Is(err, approval.ErrConflict) {
+		t.Errorf("second Forward: got %v, want ErrConflict", err)
+	}
+	close(forwarder.release)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	if forwarder.forwarded != 1 {
+		t.Errorf("forwarded %d times, want once", forwarder.forwarded)
+	}
+	stored, err = store.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != approval.StatusForwarded || stored.LastError != "" {
+		t.Errorf("status %s, error %q, want forwarded without an error", stored.Status, stored.LastError)
+	}
+}
+
+func TestApprovalInvalidAmount(t *testing.T) {
+	policy := approval.Policy{"MWK": {{Threshold: 1000, Approvals: 2}}}
+	for _, amount := range []float64{0, -5000, math.NaN(), math.Inf(1)} {
+		if _, err := policy.Required("MWK", amount); !errors.Is(err, approval.ErrInvalidAmount) {
+			t.Errorf("Required(%g): got %v, want ErrInvalidAmount", amount, err)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package sqlitetest runs the SDK's SQL stores against SQLite. It is a
+// separate module so that the SDK itself does not depend on a driver.
+package sqlitetest
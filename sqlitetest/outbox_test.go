@@ -0,0 +1,285 @@
+package sqlitetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/outbox"
+)
+
+// outboxClient is an outbox.Client that records the calls made. Its
+// first mobile money payout is accepted but answered with a 500, as if
+// the response were lost, when failFirst is set. Lookups report the
+// payout as status, or pending.
+type outboxClient struct {
+	failFirst bool
+	status    string
+
+	mu       sync.Mutex
+	payments map[string]int
+	payouts  map[string]int
+	lookups  int
+}
+
+func (c *outboxClient) InitiatePaymentContext(ctx context.Context, request paychangu.Request) (*paychangu.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.payments == nil {
+		c.payments = make(map[string]int)
+	}
+	c.payments[request.TxRef]++
+	resp := &paychangu.Response{Status: "success"}
+	resp.Data.CheckoutURL = "https://checkout.paychangu.com/" + request.TxRef
+	return resp, nil
+}
+
+func (c *outboxClient) InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.payouts == nil {
+		c.payouts = make(map[string]int)
+	}
+	c.payouts[request.ChargeID]++
+	if c.failFirst && c.payouts[request.ChargeID] == 1 {
+		return nil, &paychangu.APIError{StatusCode: http.StatusInternalServerError}
+	}
+	resp := &paychangu.MobileMoneyPayoutResponse{Status: "success"}
+	resp.Data.Transaction.ChargeID = request.ChargeID
+	resp.Data.Transaction.Status = "pending"
+	return resp, nil
+}
+
+func (c *outboxClient) InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *outboxClient) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups++
+	if c.payouts[chargeID] == 0 {
+		return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+	}
+	status := c.status
+	if status == "" {
+		status = "pending"
+	}
+	return &paychangu.PayoutTransactionDetails{ChargeID: chargeID, Status: status}, nil
+}
+
+func (c *outboxClient) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error) {
+	return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+}
+
+func newOutbox(t *testing.T) (*outbox.Outbox, *sql.DB) {
+	t.Helper()
+	db := open(t)
+	box := outbox.New(db, paychangu.DialectQuestion)
+	if err := box.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return box, db
+}
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+	box, db := newOutbox(t)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txRef, err := box.EnqueuePayment(ctx, tx, paychangu.Request{Amount: 5000, Currency: "MWK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chargeID, err := box.EnqueueMobileMoneyPayout(ctx, tx, paychangu.MobileMoneyPayoutRequest{Mobile: "0991234567", Amount: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A message enqueued in a rolled back transaction is never executed.
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolledBack, err := box.EnqueuePayment(ctx, tx, paychangu.Request{Amount: 1, Currency: "MWK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &outboxClient{}
+	var mu sync.Mutex
+	var published []outbox.Event
+	failSink := true
+	relay := &outbox.Relay{
+		Outbox: box,
+		Client: client,
+		Sink: outbox.SinkFunc(func(ctx context.Context, e outbox.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if failSink {
+				return errors.New("sink unavailable")
+			}
+			published = append(published, e)
+			return nil
+		}),
+	}
+
+	// The calls succeed but their outcomes cannot be published yet.
+	if err := relay.Pass(ctx); err == nil {
+		t.Fatal("Pass: want the sink error")
+	}
+	m, err := box.Get(ctx, txRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != outbox.StatusSucceeded || m.Attempts != 1 || !m.PublishedAt.IsZero() {
+		t.Fatalf("payment = %+v, want succeeded after one attempt and unpublished", m)
+	}
+	var resp paychangu.Response
+	if err := json.Unmarshal(m.Result, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.CheckoutURL != "https://checkout.paychangu.com/"+txRef {
+		t.Errorf("checkout URL = %q", resp.Data.CheckoutURL)
+	}
+	if _, err := box.Get(ctx, rolledBack); !errors.Is(err, outbox.ErrNotFound) {
+		t.Errorf("Get rolled back message: err = %v, want ErrNotFound", err)
+	}
+
+	// The next pass publishes the outcomes without executing them again.
+	mu.Lock()
+	failSink = false
+	mu.Unlock()
+	if err := relay.Pass(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if client.payments[txRef] != 1 || client.payouts[chargeID] != 1 {
+		t.Errorf("calls = %v %v, want one of each", client.payments, client.payouts)
+	}
+	if len(published) != 2 {
+		t.Fatalf("published %d events, want 2", len(published))
+	}
+	for _, e := range published {
+		if e.Status != outbox.StatusSucceeded || e.Attempts != 1 {
+			t.Errorf("event = %+v, want succeeded after one attempt", e)
+		}
+	}
+	for _, id := range []string{txRef, chargeID} {
+		m, err := box.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.PublishedAt.IsZero() {
+			t.Errorf("%s not marked published", id)
+		}
+	}
+
+	// Nothing is left to do.
+	if err := relay.Pass(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 2 {
+		t.Errorf("published %d events, want 2", len(published))
+	}
+}
+
+func TestOutboxRelayRetriesPayoutOnce(t *testing.T) {
+	ctx := context.Background()
+	box, db := newOutbox(t)
+
+	chargeID, err := box.EnqueueMobileMoneyPayout(ctx, db, paychangu.MobileMoneyPayoutRequest{Mobile: "0991234567", Amount: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &outboxClient{failFirst: true}
+	relay := &outbox.Relay{Outbox: box, Client: client, RetryDelay: time.Millisecond}
+
+	if err := relay.Pass(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m, err := box.Get(ctx, chargeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != outbox.StatusPending || m.Error == "" || m.NextAttemptAt.IsZero() {
+		t.Fatalf("after a 500: %+v, want pending with an error and a next attempt", m)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := relay.Pass(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m, err = box.Get(ctx, chargeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != outbox.StatusSucceeded || m.Attempts != 2 {
+		t.Fatalf("after the retry: %+v, want succeeded after two attempts", m)
+	}
+	// The payout reached PayChangu the first time, so the retry
+	// found it instead of sending it again.
+	if client.payouts[chargeID] != 1 || client.lookups != 1 {
+		t.Errorf("sent %d times with %d lookups, want once with one lookup", client.payouts[chargeID], client.lookups)
+	}
+	var details paychangu.PayoutTransactionDetails
+	if err := json.Unmarshal(m.Result, &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.ChargeID != chargeID {
+		t.Errorf("result charge ID = %q, want %q", details.ChargeID, chargeID)
+	}
+}
+
+func TestOutboxRelayFailsPayoutFoundFailed(t *testing.T) {
+	for _, status := range []string{"failed", "reversed"} {
+		t.Run(status, func(t *testing.T) {
+			ctx := context.Background()
+			box, db := newOutbox(t)
+
+			chargeID, err := box.EnqueueMobileMoneyPayout(ctx, db, paychangu.MobileMoneyPayoutRequest{Mobile: "0991234567", Amount: 1000})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &outboxClient{failFirst: true, status: status}
+			relay := &outbox.Relay{Outbox: box, Client: client, RetryDelay: time.Millisecond}
+			for i := 0; i < 2; i++ {
+				if err := relay.Pass(ctx); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			m, err := box.Get(ctx, chargeID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Status != outbox.StatusFailed || m.Error != "outbox: payout "+status {
+				t.Fatalf("after the retry: %+v, want failed with payout %s", m, status)
+			}
+			var details paychangu.PayoutTransactionDetails
+			if err := json.Unmarshal(m.Result, &details); err != nil {
+				t.Fatal(err)
+			}
+			if details.Status != status || client.payouts[chargeID] != 1 {
+				t.Errorf("result status %q after %d sends, want %s after one", details.Status, client.payouts[chargeID], status)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package sqlitetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/santinalbrowns/paychangu"
+	"github.com/santinalbrowns/paychangu/payout"
+)
+
+// payoutClient is a payout.Client that records the mobile money payouts
+// sent, and reports the ones it has not received as not found.
+type payoutClient struct {
+	mu   sync.Mutex
+	sent map[string]int
+}
+
+func (c *payoutClient) InitiateMobileMoneyPayoutContext(ctx context.Context, request paychangu.MobileMoneyPayoutRequest) (*paychangu.MobileMoneyPayoutResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sent == nil {
+		c.sent = make(map[string]int)
+	}
+	c.sent[request.ChargeID]++
+	resp := &paychangu.MobileMoneyPayoutResponse{Status: "success"}
+	resp.Data.Transaction.ChargeID = request.ChargeID
+	resp.Data.Transaction.Status = "pending"
+	return resp, nil
+}
+
+func (c *payoutClient) InitiateBankPayoutContext(ctx context.Context, request paychangu.BankPayoutRequest) (*paychangu.BankPayoutResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *payoutClient) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.PayoutTransactionDetails, error) {
+	if c.count(chargeID) == 0 {
+		return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+	}
+	return &paychangu.PayoutTransactionDetails{ChargeID: chargeID, Status: "pending"}, nil
+}
+
+func (c *payoutClient) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*paychangu.BankPayoutTransactionDetails, error) {
+	return nil, &paychangu.APIError{StatusCode: http.StatusNotFound}
+}
+
+func (c *payoutClient) count(chargeID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sent[chargeID]
+}
+
+func newPayoutStore(t *testing.T) *payout.SQLStore {
+	t.Helper()
+	store := payout.NewSQLStore(open(t), paychangu.DialectQuestion)
+	if err := store.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestPayoutSQLStore(t *testing.T) {
+	ctx := context.Background()
+	store := newPayoutStore(t)
+	machine := &payout.Machine{Store: store, Client: &payoutClient{}}
+
+	p, err := machine.DraftMobileMoney(ctx, paychangu.NewMobileMoneyPayoutRequest("0991234567", "op", 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := machine.Approve(ctx, p.ID, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Get(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != payout.StateApproved || got.Version != 1 || got.Amount() != 5000 {
+		t.Errorf("got state %s, version %d, amount %v; want approved, 1, 5000", got.State, got.Version, got.Amount())
+	}
+
+	stale := *got
+	got.LastError = "first"
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(ctx, &stale); !errors.Is(err, payout.ErrConflict) {
+		t.Errorf("update of a stale payout: got %v, want ErrConflict", err)
+	}
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, payout.ErrNotFound) {
+		t.Errorf("get of a missing payout: got %v, want ErrNotFound", err)
+	}
+
+	listed, err := store.List(ctx, payout.StateApproved, payout.StatePending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != p.ID {
+		t.Errorf("List returned %d payouts, want %s", len(listed), p.ID)
+	}
+}
+
+func TestPayoutRefreshResendsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newPayoutStore(t)
+	client := &payoutClient{}
+	machine := &payout.Machine{Store: store, Client: client, ResendAfter: time.Minute}
+
+	p, err := machine.DraftMobileMoney(ctx, paychangu.NewMobileMoneyPayoutRequest("0991234567", "op", 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := machine.Approve(ctx, p.ID, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave the payout submitted, as a crash during the initiate call would.
+	p, err = store.Get(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.State = payout.StateSubmitted
+	p.UpdatedAt = time.Now().UTC()
+	if err := store.Update(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := machine.Refresh(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.count(p.ID); n != 0 {
+		t.Fatalf("recently submitted payout sent %d times, want 0", n)
+	}
+
+	p.UpdatedAt = time.Now().Add(-time.Hour).UTC()
+	if err := store.Update(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two workers race to resend the payout; only one may send it.
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = machine.Refresh(ctx, p.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	if n := client.count(p.ID); n != 1 {
+		t.Errorf("stale payout sent %d times, want 1", n)
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, payout.ErrConflict) {
+			t.Errorf("refresh: %v", err)
+		}
+	}
+	got, err := store.Get(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != payout.StatePending || got.Attempts != 1 {
+		t.Errorf("got state %s after %d attempts, want pending after 1", got.State, got.Attempts)
+	}
+}
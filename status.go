@@ -0,0 +1,37 @@
+package paychangu
+
+import "strings"
+
+// Transaction statuses returned by NormalizeStatus.
+const (
+	StatusSuccess  = "success"
+	StatusFailed   = "failed"
+	StatusReversed = "reversed"
+	StatusPending  = "pending"
+)
+
+// NormalizeStatus maps the different spellings PayChangu uses for the
+// status of a payment or payout to StatusSuccess, StatusFailed,
+// StatusReversed or StatusPending, ignoring case and surrounding
+// spaces. Other statuses are returned trimmed and in lower case.
+//
+// Example Usage:
+//
+//	details, err := client.GetMobileMoneyPayoutDetails(chargeID)
+//	if err == nil && paychangu.NormalizeStatus(details.Status) == paychangu.StatusFailed {
+//	    // pay again with a new ChargeID
+//	}
+func NormalizeStatus(status string) string {
+	switch s := strings.ToLower(strings.TrimSpace(status)); s {
+	case "success", "successful", "completed", "complete", "paid":
+		return StatusSuccess
+	case "failed", "failure", "cancelled", "canceled", "rejected":
+		return StatusFailed
+	case "reversed":
+		return StatusReversed
+	case "pending", "processing", "initiated":
+		return StatusPending
+	default:
+		return s
+	}
+}
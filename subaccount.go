@@ -0,0 +1,148 @@
+package paychangu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// CreateSubAccount registers a sub-merchant that can receive a share of
+// payments through Request.Splits. Funds are settled to the sub-account's
+// bank account.
+//
+// Parameters:
+//
+// request (SubAccountRequest): The sub-account's business and bank details.
+//
+// Returns:
+//
+// *SubAccount: A pointer to the newly created sub-account.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	sub, err := client.CreateSubAccount(paychangu.SubAccountRequest{
+//	    BusinessName:      "Chikondi Crafts",
+//	    BankUUID:          "82310dd1-ec9b-4fe7-a32c-2f262ef08681",
+//	    BankAccountName:   "Chikondi Crafts",
+//	    BankAccountNumber: "1000000010",
+//	    Currency:          "MWK",
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to create sub-account: %v", err)
+//	}
+//	fmt.Printf("Sub-account ID: %s\n", sub.ID)
+func (p *payChangu) CreateSubAccount(request SubAccountRequest) (*SubAccount, error) {
+	return p.CreateSubAccountContext(context.Background(), request)
+}
+
+// CreateSubAccountContext is like CreateSubAccount but uses ctx for cancellation and deadlines.
+func (p *payChangu) CreateSubAccountContext(ctx context.Context, request SubAccountRequest) (*SubAccount, error) {
+	return p.saveSubAccount(ctx, http.MethodPost, "/subaccounts", request, Operation{Name: "CreateSubAccount"})
+}
+
+// UpdateSubAccount replaces the details of an existing sub-account.
+//
+// Parameters:
+//
+// subAccountID (string): The ID of the sub-account to update.
+//
+// request (SubAccountRequest): The sub-account's new details.
+//
+// Returns:
+//
+// *SubAccount: A pointer to the updated sub-account.
+//
+// error: An error, if one occurred during the request.
+func (p *payChangu) UpdateSubAccount(subAccountID string, request SubAccountRequest) (*SubAccount, error) {
+	return p.UpdateSubAccountContext(context.Background(), subAccountID, request)
+}
+
+// UpdateSubAccountContext is like UpdateSubAccount but uses ctx for cancellation and deadlines.
+func (p *payChangu) UpdateSubAccountContext(ctx context.Context, subAccountID string, request SubAccountRequest) (*SubAccount, error) {
+	return p.saveSubAccount(ctx, http.MethodPut, "/subaccounts/"+url.PathEscape(subAccountID), request, Operation{Name: "UpdateSubAccount"})
+}
+
+// saveSubAccount sends a create or update request for a sub-account.
+func (p *payChangu) saveSubAccount(ctx context.Context, method, path string, request SubAccountRequest, op Operation) (*SubAccount, error) {
+	var response SubAccountResponse
+	if err := p.call(ctx, op, method, path, request, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// ListSubAccounts retrieves all sub-accounts registered on the merchant account.
+//
+// Returns:
+//
+// []SubAccount: A slice of sub-accounts.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	subs, err := client.ListSubAccounts()
+//	if err != nil {
+//	    log.Fatalf("Failed to list sub-accounts: %v", err)
+//	}
+//	for _, s := range subs {
+//	    fmt.Printf("Sub-account: %s (%s)\n", s.BusinessName, s.ID)
+//	}
+func (p *payChangu) ListSubAccounts() ([]SubAccount, error) {
+	return p.ListSubAccountsContext(context.Background())
+}
+
+// ListSubAccountsContext is like ListSubAccounts but uses ctx for cancellation and deadlines.
+func (p *payChangu) ListSubAccountsContext(ctx context.Context) ([]SubAccount, error) {
+	var response SubAccountsResponse
+	op := Operation{Name: "ListSubAccounts"}
+	if err := p.call(ctx, op, http.MethodGet, "/subaccounts", nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// GetSubAccount retrieves a single sub-account by its ID.
+//
+// Parameters:
+//
+// subAccountID (string): The ID of the sub-account to retrieve.
+//
+// Returns:
+//
+// *SubAccount: A pointer to the sub-account.
+//
+// error: An error, if one occurred during the request.
+func (p *payChangu) GetSubAccount(subAccountID string) (*SubAccount, error) {
+	return p.GetSubAccountContext(context.Background(), subAccountID)
+}
+
+// GetSubAccountContext is like GetSubAccount but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetSubAccountContext(ctx context.Context, subAccountID string) (*SubAccount, error) {
+	var response SubAccountResponse
+	op := Operation{Name: "GetSubAccount"}
+	if err := p.call(ctx, op, http.MethodGet, "/subaccounts/"+url.PathEscape(subAccountID), nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return &response.Data, nil
+}
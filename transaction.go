@@ -1,12 +1,13 @@
 package paychangu
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 // The payChangu struct represents a client
@@ -16,6 +17,51 @@ type payChangu struct {
 	// secretkey is the secret API secretkey for
 	// authentication with the PayChangu API.
 	secretkey string
+
+	// baseURL is the API endpoint, without a trailing slash.
+	baseURL string
+
+	// httpClient sends requests once they have
+	// passed through the middlewares.
+	httpClient Doer
+
+	// maxRetries is the number of times a failed
+	// GET request is retried. Zero disables retries.
+	maxRetries int
+
+	// logger receives one record per API call.
+	// Nil disables logging.
+	logger *slog.Logger
+
+	// logOptions configures body logging for logger.
+	logOptions LogOptions
+
+	// breaker, if set, fails calls fast while the API is degraded.
+	breaker *CircuitBreaker
+
+	// limiter, if set, throttles every request sent.
+	limiter *RateLimiter
+
+	// middlewares wrap every API call, outermost first.
+	middlewares []Middleware
+
+	// idempotency, if set, guards payments and payouts
+	// against being sent twice.
+	idempotency IdempotencyStore
+
+	// idempotencyStaleAfter is how long a pending idempotency key
+	// is assumed to be in flight. Zero means the default.
+	idempotencyStaleAfter time.Duration
+
+	// idempotencyScope, if set, prefixes idempotency keys, so that
+	// the clients of a Registry sharing a store keep apart.
+	idempotencyScope string
+
+	// guard, if set, vets every payout before it is sent.
+	guard PayoutGuard
+
+	// doer sends requests through the middlewares.
+	doer Doer
 }
 
 // The New function initializes
@@ -23,9 +69,20 @@ type payChangu struct {
 //
 // secretKey (string): The secret API key used to authenticate with PayChangu.
 //
+// opts (...Option): Optional settings such as WithLogger or WithRetries.
+//
 // A pointer to a new payChangu instance, configured with the provided API key.
-func New(secretKey string) *payChangu {
-	return &payChangu{secretkey: secretKey}
+func New(secretKey string, opts ...Option) *payChangu {
+	p := &payChangu{
+		secretkey:  secretKey,
+		baseURL:    defaultBaseURL,
+		httpClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.doer = p.chain()
+	return p
 }
 
 // The InitiatePayment method sends a payment initiation request to the
@@ -55,45 +112,24 @@ func New(secretKey string) *payChangu {
 //	}
 //	fmt.Printf("Payment successful, redirect to: %s\n", resp.Data.CheckoutURL)
 func (p *payChangu) InitiatePayment(request Request) (*Response, error) {
-	data, err := json.Marshal(request)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", "https://api.paychangu.com/payment", bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		bo, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
+	return p.InitiatePaymentContext(context.Background(), request)
+}
 
-		return nil, errors.New(string(bo))
+// InitiatePaymentContext is like InitiatePayment but uses ctx for cancellation and deadlines.
+func (p *payChangu) InitiatePaymentContext(ctx context.Context, request Request) (*Response, error) {
+	if p.idempotency == nil || request.TxRef == "" {
+		return p.initiatePayment(ctx, request)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-
-		return nil, err
-	}
+	send := func() (*Response, error) { return p.initiatePayment(ctx, request) }
+	return idempotent(ctx, p, "payment:"+request.TxRef, request, nil, send, nil)
+}
 
+// initiatePayment sends request to the API.
+func (p *payChangu) initiatePayment(ctx context.Context, request Request) (*Response, error) {
 	var response Response
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	op := Operation{Name: "InitiatePayment", TxRef: request.TxRef, Currency: request.Currency, Amount: float64(request.Amount)}
+	if err := p.call(ctx, op, http.MethodPost, "/payment", request, &response); err != nil {
 		return nil, err
 	}
 
@@ -123,34 +159,14 @@ func (p *payChangu) InitiatePayment(request Request) (*Response, error) {
 //	}
 //	fmt.Printf("Payment status: %s\n", verifyResp.Data.Status)
 func (p *payChangu) VerifyPayment(txRef string) (*VerifyPaymentResponse, error) {
-	url := fmt.Sprintf("https://api.paychangu.com/verify-payment/%s", txRef)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var response Error
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return nil, err
-		}
-
-		return nil, fmt.Errorf(response.Message)
-	}
+	return p.VerifyPaymentContext(context.Background(), txRef)
+}
 
+// VerifyPaymentContext is like VerifyPayment but uses ctx for cancellation and deadlines.
+func (p *payChangu) VerifyPaymentContext(ctx context.Context, txRef string) (*VerifyPaymentResponse, error) {
 	var response VerifyPaymentResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	op := Operation{Name: "VerifyPayment", TxRef: txRef}
+	if err := p.call(ctx, op, http.MethodGet, "/verify-payment/"+url.PathEscape(txRef), nil, &response); err != nil {
 		return nil, err
 	}
 
@@ -180,37 +196,15 @@ func (p *payChangu) VerifyPayment(txRef string) (*VerifyPaymentResponse, error)
 //	    fmt.Printf("Operator: %s (Ref ID: %s)\n", op.Name, op.RefID)
 //	}
 func (p *payChangu) GetMobileMoneyOperators() ([]MobileMoneyOperator, error) {
-	req, err := http.NewRequest(http.MethodGet, "https://api.paychangu.com/mobile-money", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bo, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read error response body: %w", err)
-		}
-		// Attempt to unmarshal into general Error struct for consistent error messages
-		var apiErr Error
-		if jsonErr := json.Unmarshal(bo, &apiErr); jsonErr == nil && apiErr.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, apiErr.Message)
-		}
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bo))
-	}
+	return p.GetMobileMoneyOperatorsContext(context.Background())
+}
 
+// GetMobileMoneyOperatorsContext is like GetMobileMoneyOperators but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetMobileMoneyOperatorsContext(ctx context.Context) ([]MobileMoneyOperator, error) {
 	var response MobileMoneyOperatorsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	op := Operation{Name: "GetMobileMoneyOperators"}
+	if err := p.call(ctx, op, http.MethodGet, "/mobile-money", nil, &response); err != nil {
+		return nil, err
 	}
 
 	if response.Status != "success" {
@@ -251,62 +245,47 @@ func (p *payChangu) GetMobileMoneyOperators() ([]MobileMoneyOperator, error) {
 //	}
 //	fmt.Printf("Mobile Money Payout Initiated. Ref ID: %s, Status: %s\n", payoutResp.Data.Transaction.RefID, payoutResp.Data.Transaction.Status)
 func (p *payChangu) InitiateMobileMoneyPayout(request MobileMoneyPayoutRequest) (*MobileMoneyPayoutResponse, error) {
-	data, err := json.Marshal(request)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", "https://api.paychangu.com/mobile-money/payouts/initialize", bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-	req.Header.Set("Content-Type", "application/json")
+	return p.InitiateMobileMoneyPayoutContext(context.Background(), request)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// InitiateMobileMoneyPayoutContext is like InitiateMobileMoneyPayout but uses ctx for cancellation and deadlines.
+func (p *payChangu) InitiateMobileMoneyPayoutContext(ctx context.Context, request MobileMoneyPayoutRequest) (*MobileMoneyPayoutResponse, error) {
+	check := func() error {
+		return p.checkPayout(ctx, PayoutCheck{
+			Operation: "InitiateMobileMoneyPayout",
+			ChargeID:  request.ChargeID,
+			Amount:    request.Amount,
+			Currency:  payoutCurrency,
+			Recipient: request.Mobile,
+			Provider:  request.MobileMoneyOperatorRefID,
+		})
+	}
+	if p.idempotency == nil || request.ChargeID == "" {
+		if err := check(); err != nil {
+			return nil, err
+		}
+		return p.initiateMobileMoneyPayout(ctx, request)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK { // API typically returns 200 for successful initiation, 400 for errors
-		bo, err := io.ReadAll(resp.Body)
+	send := func() (*MobileMoneyPayoutResponse, error) { return p.initiateMobileMoneyPayout(ctx, request) }
+	reconcile := func() (*MobileMoneyPayoutResponse, error) {
+		details, err := p.GetMobileMoneyPayoutDetailsContext(ctx, request.ChargeID)
+		if notFound(err) {
+			return nil, nil
+		}
 		if err != nil {
 			return nil, err
 		}
-
-		var apiErr MobileMoneyPayoutErrorResponse
-		if jsonErr := json.Unmarshal(bo, &apiErr); jsonErr == nil && apiErr.Status == "failed" {
-			// For validation errors, the "message" field is a map
-			var errorMessages []string
-			if apiErr.Message != nil {
-				for field, messages := range apiErr.Message {
-					for _, msg := range messages {
-						errorMessages = append(errorMessages, fmt.Sprintf("%s: %s", field, msg))
-					}
-				}
-			}
-			if len(errorMessages) > 0 {
-				return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errors.Join(errors.New("validation failed"), errors.New(string(bo))).Error())
-			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(bo))
-		}
-
-		// Fallback for other non-200 statuses or unexpected error formats
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bo))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+		return &MobileMoneyPayoutResponse{Status: "success", Data: MobileMoneyPayoutData{Transaction: *details}}, nil
 	}
+	return idempotent(ctx, p, "mobile_money_payout:"+request.ChargeID, request, check, send, reconcile)
+}
 
+// initiateMobileMoneyPayout sends request to the API.
+func (p *payChangu) initiateMobileMoneyPayout(ctx context.Context, request MobileMoneyPayoutRequest) (*MobileMoneyPayoutResponse, error) {
 	var response MobileMoneyPayoutResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	op := Operation{Name: "InitiateMobileMoneyPayout", ChargeID: request.ChargeID, Currency: payoutCurrency, Amount: request.Amount}
+	if err := p.call(ctx, op, http.MethodPost, "/mobile-money/payouts/initialize", request, &response); err != nil {
 		return nil, err
 	}
 
@@ -340,37 +319,15 @@ func (p *payChangu) InitiateMobileMoneyPayout(request MobileMoneyPayoutRequest)
 //	fmt.Printf("Payout Details for Charge ID %s: Status: %s, Amount: %.2f %s\n",
 //	    payoutDetails.ChargeID, payoutDetails.Status, payoutDetails.Amount, payoutDetails.Currency)
 func (p *payChangu) GetMobileMoneyPayoutDetails(chargeID string) (*PayoutTransactionDetails, error) {
-	url := fmt.Sprintf("https://api.paychangu.com/mobile-money/payments/%sdetails", chargeID)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bo, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		var apiErr Error // Using the general Error struct for non-200 responses
-		if jsonErr := json.Unmarshal(bo, &apiErr); jsonErr == nil && apiErr.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, apiErr.Message)
-		}
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bo))
-	}
+	return p.GetMobileMoneyPayoutDetailsContext(context.Background(), chargeID)
+}
 
+// GetMobileMoneyPayoutDetailsContext is like GetMobileMoneyPayoutDetails but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetMobileMoneyPayoutDetailsContext(ctx context.Context, chargeID string) (*PayoutTransactionDetails, error) {
 	var response GetMobileMoneyPayoutDetailsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	op := Operation{Name: "GetMobileMoneyPayoutDetails", ChargeID: chargeID}
+	path := fmt.Sprintf("/mobile-money/payments/%s/details", url.PathEscape(chargeID))
+	if err := p.call(ctx, op, http.MethodGet, path, nil, &response); err != nil {
 		return nil, err
 	}
 
@@ -404,37 +361,15 @@ func (p *payChangu) GetMobileMoneyPayoutDetails(chargeID string) (*PayoutTransac
 //	    fmt.Printf("Bank: %s (UUID: %s)\n", bank.Name, bank.UUID)
 //	}
 func (p *payChangu) GetSupportedBanks(currency string) ([]Bank, error) {
-	url := fmt.Sprintf("https://api.paychangu.com/direct-charge/payouts/supported-banks?currency=%s", currency)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bo, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		var apiErr Error // Using the general Error struct for non-200 responses
-		if jsonErr := json.Unmarshal(bo, &apiErr); jsonErr == nil && apiErr.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, apiErr.Message)
-		}
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bo))
-	}
+	return p.GetSupportedBanksContext(context.Background(), currency)
+}
 
+// GetSupportedBanksContext is like GetSupportedBanks but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetSupportedBanksContext(ctx context.Context, currency string) ([]Bank, error) {
 	var response BanksResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	op := Operation{Name: "GetSupportedBanks", Currency: currency}
+	path := "/direct-charge/payouts/supported-banks?currency=" + url.QueryEscape(currency)
+	if err := p.call(ctx, op, http.MethodGet, path, nil, &response); err != nil {
 		return nil, err
 	}
 
@@ -476,6 +411,44 @@ func (p *payChangu) GetSupportedBanks(currency string) ([]Bank, error) {
 //	fmt.Printf("Bank Payout Initiated. Charge ID: %s, Status: %s\n", bankPayoutResp.Data.Transaction.ChargeID, bankPayoutResp.Data.Transaction.Status)
 //	fmt.Printf("Recipient Bank: %s, Account: %s\n", bankPayoutResp.Data.Transaction.RecipientAccountDetails.BankName, bankPayoutResp.Data.Transaction.RecipientAccountDetails.AccountNumber)
 func (p *payChangu) InitiateBankPayout(request BankPayoutRequest) (*BankPayoutResponse, error) {
+	return p.InitiateBankPayoutContext(context.Background(), request)
+}
+
+// InitiateBankPayoutContext is like InitiateBankPayout but uses ctx for cancellation and deadlines.
+func (p *payChangu) InitiateBankPayoutContext(ctx context.Context, request BankPayoutRequest) (*BankPayoutResponse, error) {
+	check := func() error {
+		return p.checkPayout(ctx, PayoutCheck{
+			Operation: "InitiateBankPayout",
+			ChargeID:  request.ChargeID,
+			Amount:    request.Amount,
+			Currency:  payoutCurrency,
+			Recipient: request.BankAccountNumber,
+			Provider:  request.BankUUID,
+		})
+	}
+	if p.idempotency == nil || request.ChargeID == "" {
+		if err := check(); err != nil {
+			return nil, err
+		}
+		return p.initiateBankPayout(ctx, request)
+	}
+
+	send := func() (*BankPayoutResponse, error) { return p.initiateBankPayout(ctx, request) }
+	reconcile := func() (*BankPayoutResponse, error) {
+		details, err := p.GetBankPayoutDetailsContext(ctx, request.ChargeID)
+		if notFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &BankPayoutResponse{Status: "success", Data: BankPayoutData{Transaction: *details}}, nil
+	}
+	return idempotent(ctx, p, "bank_payout:"+request.ChargeID, request, check, send, reconcile)
+}
+
+// initiateBankPayout sends request to the API.
+func (p *payChangu) initiateBankPayout(ctx context.Context, request BankPayoutRequest) (*BankPayoutResponse, error) {
 	// The API expects amount as a string, so we need to format it before marshaling
 	// We'll create an anonymous struct to handle this, as modifying the original
 	// BankPayoutRequest struct's Amount field to string would be less type-safe for users.
@@ -486,6 +459,7 @@ func (p *payChangu) InitiateBankPayout(request BankPayoutRequest) (*BankPayoutRe
 		ChargeID          string `json:"charge_id"`
 		BankAccountName   string `json:"bank_account_name"`
 		BankAccountNumber string `json:"bank_account_number"`
+		CustomerID        string `json:"customer_id,omitempty"`
 		Email             string `json:"email,omitempty"`
 		FirstName         string `json:"first_name,omitempty"`
 		LastName          string `json:"last_name,omitempty"`
@@ -496,72 +470,15 @@ func (p *payChangu) InitiateBankPayout(request BankPayoutRequest) (*BankPayoutRe
 		ChargeID:          request.ChargeID,
 		BankAccountName:   request.BankAccountName,
 		BankAccountNumber: request.BankAccountNumber,
+		CustomerID:        request.CustomerID,
 		Email:             request.Email,
 		FirstName:         request.FirstName,
 		LastName:          request.LastName,
 	}
 
-	data, err := json.Marshal(requestPayload)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", "https://api.paychangu.com/direct-charge/payouts/initialize", bytes.NewBuffer(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK { // Assuming 200 OK for success, and other codes for errors
-		bo, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read error response body: %w", err)
-		}
-
-		// Try to unmarshal into MobileMoneyPayoutErrorResponse which handles map[string][]string for validation errors
-		var apiErr MobileMoneyPayoutErrorResponse
-		if jsonErr := json.Unmarshal(bo, &apiErr); jsonErr == nil && apiErr.Status == "failed" {
-			// If message is a map (validation error), format it
-			var errorMessages []string
-			if apiErr.Message != nil {
-				for field, messages := range apiErr.Message {
-					for _, msg := range messages {
-						errorMessages = append(errorMessages, fmt.Sprintf("%s: %s", field, msg))
-					}
-				}
-			}
-			if len(errorMessages) > 0 {
-				return nil, fmt.Errorf("API error (%d): validation failed: %s", resp.StatusCode, errors.Join(errors.New("validation failed"), errors.New(string(bo))).Error())
-			}
-		}
-
-		// Fallback to general error struct or raw body if specific unmarshal fails
-		var generalErr Error
-		if jsonErr := json.Unmarshal(bo, &generalErr); jsonErr == nil && generalErr.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, generalErr.Message)
-		}
-
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bo))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var response BankPayoutResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	op := Operation{Name: "InitiateBankPayout", ChargeID: request.ChargeID, Currency: payoutCurrency, Amount: request.Amount}
+	if err := p.call(ctx, op, http.MethodPost, "/direct-charge/payouts/initialize", requestPayload, &response); err != nil {
 		return nil, err
 	}
 
@@ -595,43 +512,19 @@ func (p *payChangu) InitiateBankPayout(request BankPayoutRequest) (*BankPayoutRe
 //	fmt.Printf("Bank Payout Details for Charge ID %s: Status: %s, Amount: %.2f %s\n",
 //	    bankPayoutDetails.ChargeID, bankPayoutDetails.Status, bankPayoutDetails.Amount, bankPayoutDetails.Currency)
 func (p *payChangu) GetBankPayoutDetails(chargeID string) (*BankPayoutTransactionDetails, error) {
-	url := fmt.Sprintf("https://api.paychangu.com/direct-charge/payouts/%s/details", chargeID)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretkey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bo, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		var apiErr Error // Using the general Error struct for non-200 responses
-		if jsonErr := json.Unmarshal(bo, &apiErr); jsonErr == nil && apiErr.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, apiErr.Message)
-		}
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bo))
-	}
+	return p.GetBankPayoutDetailsContext(context.Background(), chargeID)
+}
 
+// GetBankPayoutDetailsContext is like GetBankPayoutDetails but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetBankPayoutDetailsContext(ctx context.Context, chargeID string) (*BankPayoutTransactionDetails, error) {
 	var response GetBankPayoutDetailsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	op := Operation{Name: "GetBankPayoutDetails", ChargeID: chargeID}
+	path := fmt.Sprintf("/direct-charge/payouts/%s/details", url.PathEscape(chargeID))
+	if err := p.call(ctx, op, http.MethodGet, path, nil, &response); err != nil {
 		return nil, err
 	}
 
 	// The API returns "successful" instead of "success" for the status field in the top-level response.
-	// We should check against both or just rely on HTTP status code if API behavior is consistent.
-	// For robustness, checking the specific 'status' in the body is good.
 	if response.Status != "successful" { // Note the 'successful' string
 		return nil, errors.New(response.Message)
 	}
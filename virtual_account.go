@@ -0,0 +1,135 @@
+package paychangu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// CreateVirtualAccount creates a bank account number that customers can pay
+// into by bank transfer. Set CustomerID for a dedicated account per customer,
+// or TxRef (and optionally Amount) for a single-use account per invoice.
+//
+// Parameters:
+//
+// request (VirtualAccountRequest): The virtual account details.
+//
+// Returns:
+//
+// *VirtualAccount: A pointer to the newly created virtual account.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	account, err := client.CreateVirtualAccount(paychangu.VirtualAccountRequest{
+//	    AccountName: "Acme Ltd",
+//	    Currency:    "MWK",
+//	    CustomerID:  "CUS_8a7d2f",
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to create virtual account: %v", err)
+//	}
+//	fmt.Printf("Pay into %s at %s\n", account.AccountNumber, account.BankName)
+func (p *payChangu) CreateVirtualAccount(request VirtualAccountRequest) (*VirtualAccount, error) {
+	return p.CreateVirtualAccountContext(context.Background(), request)
+}
+
+// CreateVirtualAccountContext is like CreateVirtualAccount but uses ctx for cancellation and deadlines.
+func (p *payChangu) CreateVirtualAccountContext(ctx context.Context, request VirtualAccountRequest) (*VirtualAccount, error) {
+	var response VirtualAccountResponse
+	op := Operation{Name: "CreateVirtualAccount", TxRef: request.TxRef, Currency: request.Currency, Amount: request.Amount}
+	if err := p.call(ctx, op, http.MethodPost, "/virtual-accounts", request, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// GetVirtualAccount retrieves a virtual account by its ID.
+//
+// Parameters:
+//
+// accountID (string): The ID of the virtual account to retrieve.
+//
+// Returns:
+//
+// *VirtualAccount: A pointer to the virtual account.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	account, err := client.GetVirtualAccount("VA_4c1e9b")
+//	if err != nil {
+//	    log.Fatalf("Failed to get virtual account: %v", err)
+//	}
+//	fmt.Printf("Account %s is %s\n", account.AccountNumber, account.Status)
+func (p *payChangu) GetVirtualAccount(accountID string) (*VirtualAccount, error) {
+	return p.GetVirtualAccountContext(context.Background(), accountID)
+}
+
+// GetVirtualAccountContext is like GetVirtualAccount but uses ctx for cancellation and deadlines.
+func (p *payChangu) GetVirtualAccountContext(ctx context.Context, accountID string) (*VirtualAccount, error) {
+	var response VirtualAccountResponse
+	op := Operation{Name: "GetVirtualAccount"}
+	if err := p.call(ctx, op, http.MethodGet, "/virtual-accounts/"+url.PathEscape(accountID), nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// ListVirtualAccountTransfers retrieves the bank transfers received into a
+// virtual account. Each transfer is reported as a PaymentDetails record with
+// its own TxRef, so it can be confirmed with VerifyPayment like a checkout payment.
+//
+// Parameters:
+//
+// accountID (string): The ID of the virtual account.
+//
+// Returns:
+//
+// []PaymentDetails: A slice of incoming transfers.
+//
+// error: An error, if one occurred during the request.
+//
+// Example Usage:
+//
+//	client := paychangu.New("your_secret_key")
+//	transfers, err := client.ListVirtualAccountTransfers("VA_4c1e9b")
+//	if err != nil {
+//	    log.Fatalf("Failed to list transfers: %v", err)
+//	}
+//	for _, t := range transfers {
+//	    fmt.Printf("%s: %.2f %s (%s)\n", t.TxRef, t.Amount, t.Currency, t.Status)
+//	}
+func (p *payChangu) ListVirtualAccountTransfers(accountID string) ([]PaymentDetails, error) {
+	return p.ListVirtualAccountTransfersContext(context.Background(), accountID)
+}
+
+// ListVirtualAccountTransfersContext is like ListVirtualAccountTransfers but uses ctx for cancellation and deadlines.
+func (p *payChangu) ListVirtualAccountTransfersContext(ctx context.Context, accountID string) ([]PaymentDetails, error) {
+	var response VirtualAccountTransfersResponse
+	op := Operation{Name: "ListVirtualAccountTransfers"}
+	if err := p.call(ctx, op, http.MethodGet, "/virtual-accounts/"+url.PathEscape(accountID)+"/transactions", nil, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "success" {
+		return nil, errors.New(response.Message)
+	}
+
+	return response.Data, nil
+}
@@ -0,0 +1,153 @@
+package paychangu
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrInvalidSignature is returned when a webhook's
+// signature does not match its payload.
+var ErrInvalidSignature = errors.New("paychangu: invalid webhook signature")
+
+// SignatureHeader is the header PayChangu sends the webhook
+// signature in: the hex encoded HMAC-SHA256 of the raw request
+// body, keyed with the webhook secret.
+const SignatureHeader = "Signature"
+
+// maxWebhookSize limits the size of webhook bodies that are read.
+const maxWebhookSize = 1 << 20
+
+// WebhookEvent is a notification sent by PayChangu
+// when a payment or payout changes status.
+type WebhookEvent struct {
+	EventType string      `json:"event_type"`
+	Status    string      `json:"status"`
+	TxRef     string      `json:"tx_ref,omitempty"`
+	ChargeID  string      `json:"charge_id,omitempty"`
+	Reference string      `json:"reference,omitempty"`
+	Currency  string      `json:"currency"`
+	Amount    json.Number `json:"amount"`
+	Mode      string      `json:"mode,omitempty"`
+	Type      string      `json:"type,omitempty"`
+
+	// Raw is the unmodified payload, for fields not covered above.
+	Raw json.RawMessage `json:"-"`
+}
+
+// Ref returns the TxRef of a payment event or the ChargeID of a payout event.
+func (e *WebhookEvent) Ref() string {
+	if e.TxRef != "" {
+		return e.TxRef
+	}
+	return e.ChargeID
+}
+
+// SignWebhook returns the signature of payload for secret,
+// as sent by PayChangu in the Signature header.
+func SignWebhook(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks that signature was made from
+// payload with secret. It returns ErrInvalidSignature otherwise.
+func VerifyWebhookSignature(payload []byte, signature, secret string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// decodeWebhook decodes payload without checking its signature.
+func decodeWebhook(payload []byte) (*WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook: %w", err)
+	}
+	event.Raw = append(json.RawMessage(nil), payload...)
+	return &event, nil
+}
+
+// ParseWebhook verifies the signature of payload with secret and decodes it.
+func ParseWebhook(payload []byte, signature, secret string) (*WebhookEvent, error) {
+	if err := VerifyWebhookSignature(payload, signature, secret); err != nil {
+		return nil, err
+	}
+	return decodeWebhook(payload)
+}
+
+// WebhookHandler handles a verified webhook event. Returning an error
+// responds with a 500 status so that PayChangu delivers the event again.
+type WebhookHandler func(ctx context.Context, event *WebhookEvent) error
+
+// NewWebhookHandler returns an http.Handler that verifies incoming
+// webhooks with secret and passes them to handle. Webhooks with an
+// invalid signature are answered with 401 Unauthorized.
+//
+// Example Usage:
+//
+//	http.Handle("/webhooks/paychangu", paychangu.NewWebhookHandler(secret,
+//	    func(ctx context.Context, event *paychangu.WebhookEvent) error {
+//	        return orders.MarkPaid(ctx, event.TxRef, event.Status)
+//	    }))
+func NewWebhookHandler(secret string, handle WebhookHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := readWebhook(w, r)
+		if err != nil {
+			return
+		}
+		event, err := ParseWebhook(payload, r.Header.Get(SignatureHeader), secret)
+		if err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		serveWebhook(w, r, event, handle)
+	})
+}
+
+// readWebhook reads the body of a webhook request, answering
+// the request itself if it is not a POST or cannot be read.
+func readWebhook(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, errors.New("method not allowed")
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return nil, err
+	}
+	return payload, nil
+}
+
+// writeWebhookError answers a webhook that failed verification or decoding.
+func writeWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidSignature) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// serveWebhook passes event to handle and answers the request.
+func serveWebhook(w http.ResponseWriter, r *http.Request, event *WebhookEvent, handle WebhookHandler) {
+	if err := handle(r.Context(), event); err != nil {
+		http.Error(w, "failed to handle webhook", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}